@@ -3,6 +3,7 @@ package main
 import (
   "log"
   "net/http"
+  "os"
 
   "go-url-shortener/internal/routes"
 )
@@ -11,7 +12,13 @@ func main(){
   dbPath := "./urls.db"
   redisAddr := "localhost:6379"
 
-  router, err := routes.SetupRouter(dbPath, redisAddr)
+  driver := os.Getenv("STORAGE_DRIVER")
+  dsn := os.Getenv("STORAGE_DSN")
+  if dsn == "" {
+    dsn = dbPath
+  }
+
+  router, err := routes.SetupRouter(driver, dsn, redisAddr)
   if err != nil {
     log.Fatalf("Failed to set up router: %v", err)
   }
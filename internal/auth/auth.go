@@ -0,0 +1,70 @@
+package auth
+
+import (
+  "errors"
+  "os"
+  "time"
+
+  "github.com/golang-jwt/jwt/v5"
+  "golang.org/x/crypto/bcrypt"
+)
+
+// Secret used to sign JWTs issued on register/login. Falls back to a fixed
+// dev secret (so local runs and tests don't need JWT_SECRET set), but
+// anything beyond local dev must set the env var
+var jwtSecret = []byte(loadJWTSecret())
+
+func loadJWTSecret() string {
+  if secret := os.Getenv("JWT_SECRET"); secret != "" {
+    return secret
+  }
+  return "shortify-dev-secret"
+}
+
+// Claims embedded in every JWT issued by the auth subsystem
+type Claims struct {
+  UserID int64 `json:"user_id"`
+  jwt.RegisteredClaims
+}
+
+// Hashes a plaintext password for storage
+func HashPassword(password string) (string, error) {
+  hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+  if err != nil {
+    return "", err
+  }
+  return string(hash), nil
+}
+
+// Compares a plaintext password against its stored hash
+func CheckPassword(hash, password string) bool {
+  return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// Issues a signed JWT for the given user id
+func GenerateToken(userID int64) (string, error) {
+  claims := Claims{
+    UserID: userID,
+    RegisteredClaims: jwt.RegisteredClaims{
+      ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+      IssuedAt:  jwt.NewNumericDate(time.Now()),
+    },
+  }
+
+  token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+  return token.SignedString(jwtSecret)
+}
+
+// Validates a JWT and returns the embedded user id
+func ParseToken(tokenString string) (int64, error) {
+  claims := &Claims{}
+
+  token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+    return jwtSecret, nil
+  })
+  if err != nil || !token.Valid {
+    return 0, errors.New("Invalid or expired token")
+  }
+
+  return claims.UserID, nil
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+  "crypto/rand"
+  "database/sql"
+  "encoding/hex"
+  "encoding/json"
+  "fmt"
+  "net/http"
+
+  "go-url-shortener/internal/auth"
+)
+
+// Handles request to register a new user
+func (h *URLShortenerHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+  var body struct {
+    Email    string `json:"email"`
+    Password string `json:"password"`
+  }
+
+  if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+    http.Error(w, "Invalid request body: Please provide a valid JSON payload", http.StatusBadRequest)
+    return
+  }
+
+  if body.Email == "" || body.Password == "" {
+    http.Error(w, "Missing required field: 'email' and 'password' cannot be empty", http.StatusBadRequest)
+    return
+  }
+
+  passwordHash, err := auth.HashPassword(body.Password)
+  if err != nil {
+    http.Error(w, "Failed to process password", http.StatusInternalServerError)
+    return
+  }
+
+  apiToken, err := generateAPIToken()
+  if err != nil {
+    http.Error(w, "Failed to process registration", http.StatusInternalServerError)
+    return
+  }
+
+  userID, err := h.Shortener.insertUser(body.Email, passwordHash, apiToken)
+  if err != nil {
+    http.Error(w, "Email already registered", http.StatusConflict)
+    return
+  }
+
+  token, err := auth.GenerateToken(userID)
+  if err != nil {
+    http.Error(w, "Failed to issue token", http.StatusInternalServerError)
+    return
+  }
+
+  response := map[string]string{
+    "token": token,
+  }
+
+  w.Header().Set("Content-Type", "application/json")
+  json.NewEncoder(w).Encode(response)
+}
+
+// Handles request to log an existing user in
+func (h *URLShortenerHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+  var body struct {
+    Email    string `json:"email"`
+    Password string `json:"password"`
+  }
+
+  if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+    http.Error(w, "Invalid request body: Please provide a valid JSON payload", http.StatusBadRequest)
+    return
+  }
+
+  var userID int64
+  var passwordHash string
+  query := fmt.Sprintf(`SELECT id, password_hash FROM users WHERE email = %s`, h.Shortener.placeholder(1))
+  err := h.Shortener.db.QueryRow(query, body.Email).Scan(&userID, &passwordHash)
+  if err == sql.ErrNoRows {
+    http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+    return
+  } else if err != nil {
+    http.Error(w, "Database error", http.StatusInternalServerError)
+    return
+  }
+
+  if !auth.CheckPassword(passwordHash, body.Password) {
+    http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+    return
+  }
+
+  token, err := auth.GenerateToken(userID)
+  if err != nil {
+    http.Error(w, "Failed to issue token", http.StatusInternalServerError)
+    return
+  }
+
+  response := map[string]string{
+    "token": token,
+  }
+
+  w.Header().Set("Content-Type", "application/json")
+  json.NewEncoder(w).Encode(response)
+}
+
+// Generates the random hex token stored as a new user's api_token
+func generateAPIToken() (string, error) {
+  raw := make([]byte, 16)
+  if _, err := rand.Read(raw); err != nil {
+    return "", err
+  }
+  return hex.EncodeToString(raw), nil
+}
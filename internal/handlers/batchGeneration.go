@@ -0,0 +1,124 @@
+package handlers
+
+import (
+  "context"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "net/http"
+  "time"
+
+  "go-url-shortener/internal/middleware"
+  "go-url-shortener/internal/storage"
+)
+
+// A single entry in a POST /generate/batch request body
+type BatchURLRequest struct {
+  OriginalURL    string `json:"original_url"`
+  CustomShortURL string `json:"custom_short_url,omitempty"`
+  ExpirationTime string `json:"expiration_time,omitempty"`
+}
+
+// A single entry in a POST /generate/batch response, indexed the same as
+// the matching request entry. Error is set instead of ShortURL when an
+// individual entry is invalid
+type BatchURLResult struct {
+  ShortURL string `json:"short_url,omitempty"`
+  Error    string `json:"error,omitempty"`
+}
+
+// Handles request to generate many short URLs, storing them with a single
+// BatchPut transaction instead of one insert per URL
+func (h *URLShortenerHandler) BatchGenerateHandler(w http.ResponseWriter, r *http.Request) {
+  var body []BatchURLRequest
+  if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+    http.Error(w, "Invalid request body: Please provide a valid JSON array", http.StatusBadRequest)
+    return
+  }
+
+  if len(body) == 0 {
+    http.Error(w, "Missing required field: request body cannot be an empty array", http.StatusBadRequest)
+    return
+  }
+
+  userID, _ := middleware.UserIDFromContext(r.Context())
+
+  results, err := h.Shortener.BatchGenerateShortURLs(body, userID)
+  if err != nil {
+    http.Error(w, fmt.Sprintf("Failed to generate short URLs: %v", err), http.StatusInternalServerError)
+    return
+  }
+
+  w.Header().Set("Content-Type", "application/json")
+  json.NewEncoder(w).Encode(results)
+}
+
+// Generates short URLs for each entry in requests and stores them with a
+// single BatchPut, instead of going through GenerateShortURL one insert at
+// a time. Per-entry validation errors are reported back
+// positionally rather than failing the whole batch
+func (us *URLShortener) BatchGenerateShortURLs(requests []BatchURLRequest, userID int64) ([]BatchURLResult, error) {
+  ctx := context.Background()
+
+  records := make([]storage.URLRecord, 0, len(requests))
+  // Tracks which results[] index each records[] entry belongs to, so a
+  // BatchPut failure can be retried one record at a time and map each
+  // individual failure back to its original request position
+  recordIdx := make([]int, 0, len(requests))
+  results := make([]BatchURLResult, len(requests))
+
+  for i, req := range requests {
+    if req.OriginalURL == "" {
+      results[i] = BatchURLResult{Error: "Missing required field: 'original_url' cannot be empty"}
+      continue
+    }
+
+    var expirationTime *time.Time
+    if req.ExpirationTime != "" {
+      parsed, err := time.Parse(time.RFC3339, req.ExpirationTime)
+      if err != nil {
+        results[i] = BatchURLResult{Error: "Invalid 'expiration_time' format"}
+        continue
+      }
+      expirationTime = &parsed
+    }
+
+    shortURL := req.CustomShortURL
+    if shortURL != "" && !isValidCustomURL(shortURL) {
+      results[i] = BatchURLResult{Error: "Invalid characters in custom short URL"}
+      continue
+    }
+
+    if shortURL == "" {
+      id, err := us.nextShortURLID(ctx)
+      if err != nil {
+        return nil, err
+      }
+      shortURL = encodeBase62(id)
+    }
+
+    records = append(records, storage.URLRecord{ShortURL: shortURL, OriginalURL: req.OriginalURL, ExpirationTime: expirationTime, UserID: userID})
+    recordIdx = append(recordIdx, i)
+    results[i] = BatchURLResult{ShortURL: shortURL}
+  }
+
+  if len(records) > 0 {
+    if err := us.store.BatchPut(ctx, records); err != nil {
+      // The transaction rolled back as a whole (e.g. one entry's custom
+      // short URL collided with an existing row); fall back to inserting
+      // one at a time so the entries with nothing wrong with them still
+      // succeed, and the offending entry gets its own reported error
+      for j, record := range records {
+        if err := us.store.Put(ctx, record); err != nil {
+          if errors.Is(err, storage.ErrShortURLExists) {
+            results[recordIdx[j]] = BatchURLResult{Error: "Custom short URL already exists"}
+          } else {
+            results[recordIdx[j]] = BatchURLResult{Error: fmt.Sprintf("Failed to store short URL: %v", err)}
+          }
+        }
+      }
+    }
+  }
+
+  return results, nil
+}
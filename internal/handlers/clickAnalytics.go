@@ -0,0 +1,296 @@
+package handlers
+
+import (
+  "context"
+  "crypto/sha256"
+  "encoding/hex"
+  "encoding/json"
+  "fmt"
+  "log"
+  "net"
+  "net/http"
+  "time"
+
+  "github.com/gorilla/mux"
+  "github.com/redis/go-redis/v9"
+)
+
+// How many click events may queue up before recordClick starts dropping them
+const clickEventBufferSize = 1000
+
+// How often Redis hot-path counters are flushed into the click_stats table
+const clickFlushInterval = 5 * time.Minute
+
+// A single resolve event queued for asynchronous recording
+type ClickEvent struct {
+  ShortURL  string
+  Timestamp time.Time
+  IPHash    string
+  UserAgent string
+  Referer   string
+  Country   string
+}
+
+// Response payload for GET /{shortURL}/stats
+type ClickStats struct {
+  ShortURL       string         `json:"short_url"`
+  TotalClicks    int64          `json:"total_clicks"`
+  UniqueVisitors int64          `json:"unique_visitors"`
+  ClicksByHour   map[string]int `json:"clicks_by_hour"`
+  ClicksByDay    map[string]int `json:"clicks_by_day"`
+  ClicksByWeek   map[string]int `json:"clicks_by_week"`
+  TopReferers    []CountedValue `json:"top_referers"`
+  TopUserAgents  []CountedValue `json:"top_user_agents"`
+}
+
+// A value paired with its occurrence count, used for the stats top-N lists
+type CountedValue struct {
+  Value string `json:"value"`
+  Count int    `json:"count"`
+}
+
+// Queues a click event for asynchronous recording, never blocking the redirect path
+func (us *URLShortener) recordClick(shortURL string, r *http.Request) {
+  event := ClickEvent{
+    ShortURL:  shortURL,
+    Timestamp: time.Now(),
+    IPHash:    hashIP(clientIP(r)),
+    UserAgent: r.UserAgent(),
+    Referer:   r.Referer(),
+    Country:   r.Header.Get("CF-IPCountry"),
+  }
+
+  select {
+  case us.clickEvents <- event:
+  default:
+    log.Printf("[Clicks] Dropping click event for %s: buffer full", shortURL)
+  }
+}
+
+// Drains queued click events, persisting them to the clicks table and bumping the
+// Redis hot-path counters used for cheap, frequent reads
+func (us *URLShortener) processClickEvents() {
+  ctx := context.Background()
+
+  insertQuery := fmt.Sprintf(`INSERT INTO clicks (short_url, timestamp, ip_hash, user_agent, referer, country) VALUES (%s)`, us.placeholders(6))
+  for event := range us.clickEvents {
+    if _, err := us.db.Exec(insertQuery, event.ShortURL, event.Timestamp, event.IPHash, event.UserAgent, event.Referer, event.Country); err != nil {
+      log.Printf("[Clicks] Failed to persist click for %s: %v", event.ShortURL, err)
+      continue
+    }
+
+    if err := us.cache.Incr(ctx, clickTotalKey(event.ShortURL)).Err(); err != nil {
+      log.Printf("[Redis] Failed to increment click counter for %s: %v", event.ShortURL, err)
+    }
+
+    if err := us.cache.SAdd(ctx, clickVisitorsKey(event.ShortURL), event.IPHash).Err(); err != nil {
+      log.Printf("[Redis] Failed to record unique visitor for %s: %v", event.ShortURL, err)
+    }
+  }
+}
+
+// Periodically persists the Redis hot-path counters into click_stats so
+// totals survive a Redis restart
+func (us *URLShortener) flushClickCounters() {
+  ticker := time.NewTicker(clickFlushInterval)
+  defer ticker.Stop()
+
+  for range ticker.C {
+    us.flushClickCountersOnce()
+  }
+}
+
+func (us *URLShortener) flushClickCountersOnce() {
+  ctx := context.Background()
+
+  rows, err := us.db.Query(`SELECT DISTINCT short_url FROM clicks`)
+  if err != nil {
+    log.Printf("[Clicks] Failed to list short URLs for counter flush: %v", err)
+    return
+  }
+  defer rows.Close()
+
+  var shortURLs []string
+  for rows.Next() {
+    var shortURL string
+    if err := rows.Scan(&shortURL); err == nil {
+      shortURLs = append(shortURLs, shortURL)
+    }
+  }
+
+  for _, shortURL := range shortURLs {
+    total, err := us.cache.Get(ctx, clickTotalKey(shortURL)).Int64()
+    if err != nil && err != redis.Nil {
+      log.Printf("[Redis] Failed to read click total for %s: %v", shortURL, err)
+      continue
+    }
+
+    uniques, err := us.cache.SCard(ctx, clickVisitorsKey(shortURL)).Result()
+    if err != nil {
+      log.Printf("[Redis] Failed to read unique visitor count for %s: %v", shortURL, err)
+      continue
+    }
+
+    upsertQuery := fmt.Sprintf(`
+    INSERT INTO click_stats (short_url, total_clicks, unique_visitors, updated_at)
+    VALUES (%s)
+    ON CONFLICT(short_url) DO UPDATE SET total_clicks = excluded.total_clicks, unique_visitors = excluded.unique_visitors, updated_at = excluded.updated_at`, us.placeholders(4))
+    if _, err := us.db.Exec(upsertQuery, shortURL, total, uniques, time.Now()); err != nil {
+      log.Printf("[Clicks] Failed to flush counters for %s: %v", shortURL, err)
+    }
+  }
+}
+
+// Handles request to fetch aggregated click analytics for a short URL
+func (h *URLShortenerHandler) StatsHandler(w http.ResponseWriter, r *http.Request) {
+  vars := mux.Vars(r)
+  shortURL := vars["shortURL"]
+  if shortURL == "" {
+    http.Error(w, "Invalid request: 'shortURL' cannot be empty", http.StatusBadRequest)
+    return
+  }
+
+  if _, exists := h.Shortener.ResolveShortURL(shortURL); !exists {
+    http.Error(w, "Short URL not found: No record exists for the given 'shortURL'", http.StatusNotFound)
+    return
+  }
+
+  stats, err := h.Shortener.computeClickStats(shortURL)
+  if err != nil {
+    http.Error(w, fmt.Sprintf("Failed to compute stats: %v", err), http.StatusInternalServerError)
+    return
+  }
+
+  w.Header().Set("Content-Type", "application/json")
+  json.NewEncoder(w).Encode(stats)
+}
+
+// Aggregates total/unique counts (preferring the Redis hot-path, falling
+// back to the last flushed click_stats snapshot) alongside time-bucketed counts
+// and top referers/user-agents computed from the raw clicks log
+func (us *URLShortener) computeClickStats(shortURL string) (*ClickStats, error) {
+  ctx := context.Background()
+
+  total, err := us.cache.Get(ctx, clickTotalKey(shortURL)).Int64()
+  if err != nil {
+    query := fmt.Sprintf(`SELECT COALESCE(total_clicks, 0) FROM click_stats WHERE short_url = %s`, us.placeholder(1))
+    if err := us.db.QueryRow(query, shortURL).Scan(&total); err != nil {
+      total = 0
+    }
+  }
+
+  // SCard reports a missing set as 0 rather than redis.Nil, so treat 0 as a miss
+  uniques, err := us.cache.SCard(ctx, clickVisitorsKey(shortURL)).Result()
+  if err != nil || uniques == 0 {
+    query := fmt.Sprintf(`SELECT COALESCE(unique_visitors, 0) FROM click_stats WHERE short_url = %s`, us.placeholder(1))
+    if err := us.db.QueryRow(query, shortURL).Scan(&uniques); err != nil {
+      uniques = 0
+    }
+  }
+
+  stats := &ClickStats{
+    ShortURL:       shortURL,
+    TotalClicks:    total,
+    UniqueVisitors: uniques,
+    ClicksByHour:   map[string]int{},
+    ClicksByDay:    map[string]int{},
+    ClicksByWeek:   map[string]int{},
+  }
+
+  buckets := []struct {
+    name string
+    dest map[string]int
+  }{
+    {"hour", stats.ClicksByHour},
+    {"day", stats.ClicksByDay},
+    {"week", stats.ClicksByWeek},
+  }
+  for _, bucket := range buckets {
+    rows, err := us.db.Query(us.bucketQuery(bucket.name), shortURL)
+    if err != nil {
+      return nil, fmt.Errorf("Database error: %v", err)
+    }
+    for rows.Next() {
+      var key string
+      var count int
+      if err := rows.Scan(&key, &count); err == nil {
+        bucket.dest[key] = count
+      }
+    }
+    rows.Close()
+  }
+
+  stats.TopReferers, err = us.topValues(shortURL, "referer")
+  if err != nil {
+    return nil, err
+  }
+
+  stats.TopUserAgents, err = us.topValues(shortURL, "user_agent")
+  if err != nil {
+    return nil, err
+  }
+
+  return stats, nil
+}
+
+// Builds the GROUP BY query that buckets a short URL's clicks by hour/day/week.
+// SQLite's strftime has no Postgres equivalent, so the two drivers use
+// different date-formatting functions entirely. Hour and day labels match
+// across drivers; week labels do not (ISO week on Postgres, Monday-based
+// %W week of the calendar year on SQLite)
+func (us *URLShortener) bucketQuery(bucket string) string {
+  if us.driver == "postgres" {
+    formats := map[string]string{"hour": "YYYY-MM-DD HH24:00", "day": "YYYY-MM-DD", "week": "YYYY-IW"}
+    return fmt.Sprintf(`SELECT to_char(timestamp, '%s') AS bucket, COUNT(*) FROM clicks WHERE short_url = $1 GROUP BY bucket`, formats[bucket])
+  }
+
+  formats := map[string]string{"hour": "%Y-%m-%d %H:00", "day": "%Y-%m-%d", "week": "%Y-%W"}
+  return fmt.Sprintf(`SELECT strftime('%s', timestamp) AS bucket, COUNT(*) FROM clicks WHERE short_url = ? GROUP BY bucket`, formats[bucket])
+}
+
+// Returns the 5 most frequent values of the given clicks column for a short URL
+func (us *URLShortener) topValues(shortURL, column string) ([]CountedValue, error) {
+  query := fmt.Sprintf(`SELECT %s, COUNT(*) AS c FROM clicks WHERE short_url = %s AND %s != '' GROUP BY %s ORDER BY c DESC LIMIT 5`, column, us.placeholder(1), column, column)
+  rows, err := us.db.Query(query, shortURL)
+  if err != nil {
+    return nil, fmt.Errorf("Database error: %v", err)
+  }
+  defer rows.Close()
+
+  var values []CountedValue
+  for rows.Next() {
+    var value string
+    var count int
+    if err := rows.Scan(&value, &count); err != nil {
+      return nil, fmt.Errorf("Failed to scan %s: %v", column, err)
+    }
+    values = append(values, CountedValue{Value: value, Count: count})
+  }
+
+  return values, nil
+}
+
+// Returns the caller's IP address, preferring X-Forwarded-For when present
+func clientIP(r *http.Request) string {
+  if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+    return forwarded
+  }
+  if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+    return host
+  }
+  return r.RemoteAddr
+}
+
+// Hashes an IP address so raw addresses are never stored at rest
+func hashIP(ip string) string {
+  sum := sha256.Sum256([]byte(ip))
+  return hex.EncodeToString(sum[:])
+}
+
+func clickTotalKey(shortURL string) string {
+  return "clicks:" + shortURL + ":total"
+}
+
+func clickVisitorsKey(shortURL string) string {
+  return "clicks:" + shortURL + ":visitors"
+}
@@ -2,56 +2,34 @@ package handlers
 
 import (
   "bytes"
-  "database/sql"
   "encoding/json"
   "net/http"
   "net/http/httptest"
-  "os"
   "testing"
   "time"
   "context"
 
   "github.com/redis/go-redis/v9"
   "github.com/gorilla/mux"
-  _ "github.com/mattn/go-sqlite3"
+
+  "go-url-shortener/internal/auth"
+  "go-url-shortener/internal/middleware"
 )
 
-// Initializes a temporary SQLite database for testing
-func setupTestDB(t *testing.T) *sql.DB {
+// Builds a handler backed by the in-memory Storage driver, so tests never
+// touch disk
+func setupTestHandler(t testing.TB) *URLShortenerHandler {
   t.Helper()
 
-  // Create a temporary SQLite database for testing
-  dbPath := "./test_urls.db"
-  os.Remove(dbPath)
-
-  db, err := sql.Open("sqlite3", dbPath)
-  if err != nil {
-    t.Fatalf("Failed to set up test database: %v", err)
-  }
-
-  createTableQuery := `
-  CREATE TABLE IF NOT EXISTS urls (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    short_url TEXT NOT NULL UNIQUE,
-    original_url TEXT NOT NULL,
-    expiration_time TIMESTAMP NULL
-  );`
-
-  _, err = db.Exec(createTableQuery)
+  handler, err := NewURLShortenerHandler("memory", ":memory:", "localhost:6379")
   if err != nil {
-    t.Fatalf("Failed to create table: %v", err)
+    t.Fatalf("Failed to initialize handler: %v", err)
   }
-
-  t.Cleanup(func() {
-    db.Close()
-    os.Remove(dbPath) 
-  })
-
-  return db
+  return handler
 }
 
 // Initializes a temporary Redis client for testing
-func setupTestRedis(t *testing.T) *redis.Client{
+func setupTestRedis(t testing.TB) *redis.Client{
   t.Helper()
 
   redisAddr := "localhost:6379"
@@ -81,13 +59,8 @@ func setupTestRedis(t *testing.T) *redis.Client{
 
 // Test the GenerateHandler
 func TestGenerateHandler(t *testing.T) {
-  _ = setupTestDB(t)
   _ = setupTestRedis(t)
-
-  handler, err := NewURLShortenerHandler("./test_urls.db", "localhost:6379")
-  if err != nil {
-    t.Fatalf("Failed to initialize handler: %v", err)
-  }
+  handler := setupTestHandler(t)
 
   tests := []struct {
     name           string
@@ -142,18 +115,13 @@ func TestGenerateHandler(t *testing.T) {
 
 // Test the ResolveHandler
 func TestResolveHandler(t *testing.T) {
-  _ = setupTestDB(t)
   _ = setupTestRedis(t)
-  
-  handler, err := NewURLShortenerHandler("./test_urls.db", "localhost:6379")
-  if err != nil {
-    t.Fatalf("Failed to initialize handler: %v", err)
-  }
+  handler := setupTestHandler(t)
 
   // Prepopulate the database with a short URL
   originalURL := "https://github.com/eliuttth-dev"
   expirationTime := time.Now().Add(1 * time.Hour)
-  shortURL, err := handler.Shortener.GenerateShortURL(originalURL, "eliuth-github-test", &expirationTime)
+  shortURL, err := handler.Shortener.GenerateShortURL(originalURL, "eliuth-github-test", &expirationTime, 0, "", nil)
   if err != nil {
     t.Fatalf("Failed to prepopulate database: %v", err)
   }
@@ -194,29 +162,21 @@ func TestResolveHandler(t *testing.T) {
     })
   }
 
-  // Ensure expiration time is set correctly in the DB
-  var dbExpiration time.Time
-  err = handler.Shortener.db.QueryRow("SELECT expiration_time FROM urls WHERE short_url = ?", "eliuth-github-test").Scan(&dbExpiration)
-  if err == sql.ErrNoRows {
-    t.Fatalf("Short URL 'eliuth-github-test' not found in database")
-  } else if err != nil {
-    t.Fatalf("Failed to query expiration time from database: %v", err)
+  // Ensure expiration time is set correctly in storage
+  record, err := handler.Shortener.store.Get(context.Background(), "eliuth-github-test")
+  if err != nil {
+    t.Fatalf("Short URL 'eliuth-github-test' not found in storage: %v", err)
   }
 
-  if !dbExpiration.Equal(expirationTime) {
-    t.Errorf("Expected expiration time %v, got %v", expirationTime, dbExpiration)
+  if record.ExpirationTime == nil || !record.ExpirationTime.Equal(expirationTime) {
+    t.Errorf("Expected expiration time %v, got %v", expirationTime, record.ExpirationTime)
   }
 }
 
 // Test URL Generation with Expiration
 func TestGenerateWithExpiration(t *testing.T) {
-  db := setupTestDB(t)
   setupTestRedis(t)
-
-  handler, err := NewURLShortenerHandler("./test_urls.db", "localhost:6379")
-  if err != nil {
-    t.Fatalf("Failed to initialize handler: %v", err)
-  }
+  handler := setupTestHandler(t)
 
   expiration := time.Now().Add(1 * time.Millisecond)
   payload := map[string]interface{}{
@@ -248,15 +208,387 @@ func TestGenerateWithExpiration(t *testing.T) {
     t.Errorf("Expected short URL 'eliuth-github-test', got %s", shortURL)
   }
 
-  // Verify expiration in the DB
-  var dbExpiration time.Time
-  err = db.QueryRow("SELECT expiration_time FROM urls WHERE short_url = ?", "eliuth-github-test").Scan(&dbExpiration)
-  
+  // Verify expiration in storage
+  record, err := handler.Shortener.store.Get(context.Background(), "eliuth-github-test")
+  if err != nil {
+    t.Fatalf("Failed to fetch stored record: %v", err)
+  }
+
+  if record.ExpirationTime == nil || !record.ExpirationTime.Round(time.Second).Equal(expiration.Round(time.Second)) {
+    t.Errorf("Expected expiration time %v, got %v", expiration, record.ExpirationTime)
+  }
+}
+
+// Test password-protected short URLs: a plain GET must not redirect, and
+// POST /{shortURL}/unlock must only redirect with the correct password
+func TestPasswordProtectedShortURL(t *testing.T) {
+  _ = setupTestRedis(t)
+  handler := setupTestHandler(t)
+
+  originalURL := "https://github.com/eliuttth-dev"
+  passwordHash, err := auth.HashPassword("s3cret")
+  if err != nil {
+    t.Fatalf("Failed to hash password: %v", err)
+  }
+
+  shortURL, err := handler.Shortener.GenerateShortURL(originalURL, "eliuth-locked", nil, 0, passwordHash, nil)
+  if err != nil {
+    t.Fatalf("Failed to prepopulate database: %v", err)
+  }
+
+  router := mux.NewRouter()
+  router.HandleFunc("/{shortURL}", handler.ResolveHandler).Methods("GET")
+  router.HandleFunc("/{shortURL}/unlock", handler.UnlockHandler).Methods("POST")
+
+  // A plain GET must not redirect
+  req := httptest.NewRequest("GET", "/"+shortURL, nil)
+  w := httptest.NewRecorder()
+  router.ServeHTTP(w, req)
+  if w.Result().StatusCode != http.StatusUnauthorized {
+    t.Errorf("Expected status %v for a locked link, got %v", http.StatusUnauthorized, w.Result().StatusCode)
+  }
+
+  // Unlocking with the wrong password must fail
+  wrongBody, _ := json.Marshal(map[string]string{"password": "wrong"})
+  req = httptest.NewRequest("POST", "/"+shortURL+"/unlock", bytes.NewBuffer(wrongBody))
+  req.Header.Set("Content-Type", "application/json")
+  w = httptest.NewRecorder()
+  router.ServeHTTP(w, req)
+  if w.Result().StatusCode != http.StatusUnauthorized {
+    t.Errorf("Expected status %v for the wrong password, got %v", http.StatusUnauthorized, w.Result().StatusCode)
+  }
+
+  // Unlocking with the correct password must redirect
+  rightBody, _ := json.Marshal(map[string]string{"password": "s3cret"})
+  req = httptest.NewRequest("POST", "/"+shortURL+"/unlock", bytes.NewBuffer(rightBody))
+  req.Header.Set("Content-Type", "application/json")
+  w = httptest.NewRecorder()
+  router.ServeHTTP(w, req)
+  resp := w.Result()
+  if resp.StatusCode != http.StatusFound {
+    t.Errorf("Expected status %v, got %v", http.StatusFound, resp.StatusCode)
+  }
+  if location := resp.Header.Get("Location"); location != originalURL {
+    t.Errorf("Expected redirect to %v, got %v", originalURL, location)
+  }
+}
+
+// Test single/limited-use short URLs: resolving must succeed up to max_uses
+// and then return 410 Gone
+func TestMaxUsesShortURL(t *testing.T) {
+  _ = setupTestRedis(t)
+  handler := setupTestHandler(t)
+
+  maxUses := int64(1)
+  shortURL, err := handler.Shortener.GenerateShortURL("https://github.com/eliuttth-dev", "eliuth-oneuse", nil, 0, "", &maxUses)
+  if err != nil {
+    t.Fatalf("Failed to prepopulate database: %v", err)
+  }
+
+  router := mux.NewRouter()
+  router.HandleFunc("/{shortURL}", handler.ResolveHandler).Methods("GET")
+
+  // First use succeeds
+  req := httptest.NewRequest("GET", "/"+shortURL, nil)
+  w := httptest.NewRecorder()
+  router.ServeHTTP(w, req)
+  if w.Result().StatusCode != http.StatusFound {
+    t.Fatalf("Expected status %v on first use, got %v", http.StatusFound, w.Result().StatusCode)
+  }
+
+  // Second use is exhausted
+  req = httptest.NewRequest("GET", "/"+shortURL, nil)
+  w = httptest.NewRecorder()
+  router.ServeHTTP(w, req)
+  if w.Result().StatusCode != http.StatusGone {
+    t.Errorf("Expected status %v once uses are exhausted, got %v", http.StatusGone, w.Result().StatusCode)
+  }
+}
+
+// Test the QRCodeHandler, covering the default PNG output, ?format=svg, and
+// the not-found case
+func TestQRCodeHandler(t *testing.T) {
+  _ = setupTestRedis(t)
+  handler := setupTestHandler(t)
+
+  shortURL, err := handler.Shortener.GenerateShortURL("https://github.com/eliuttth-dev", "eliuth-qr", nil, 0, "", nil)
+  if err != nil {
+    t.Fatalf("Failed to prepopulate database: %v", err)
+  }
+
+  router := mux.NewRouter()
+  router.HandleFunc("/{shortURL}/qr", handler.QRCodeHandler).Methods("GET")
+
+  tests := []struct {
+    name                string
+    path                string
+    expectedStatus      int
+    expectedContentType string
+  }{
+    {"Default PNG", "/" + shortURL + "/qr", http.StatusOK, "image/png"},
+    {"SVG format", "/" + shortURL + "/qr?format=svg&size=128&ecc=H&fg=ff0000&bg=ffffff", http.StatusOK, "image/svg+xml"},
+    {"Invalid format", "/" + shortURL + "/qr?format=bmp", http.StatusBadRequest, ""},
+    {"Unknown short URL", "/nonexistent/qr", http.StatusNotFound, ""},
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      req := httptest.NewRequest("GET", tt.path, nil)
+      w := httptest.NewRecorder()
+      router.ServeHTTP(w, req)
+
+      resp := w.Result()
+      defer resp.Body.Close()
+
+      if resp.StatusCode != tt.expectedStatus {
+        t.Errorf("Expected status %v, got %v", tt.expectedStatus, resp.StatusCode)
+      }
+
+      if tt.expectedContentType != "" && resp.Header.Get("Content-Type") != tt.expectedContentType {
+        t.Errorf("Expected Content-Type %v, got %v", tt.expectedContentType, resp.Header.Get("Content-Type"))
+      }
+    })
+  }
+}
+
+// Test RegisterHandler and LoginHandler: a new user can register, a
+// duplicate email is rejected, and login only succeeds with the right password
+func TestRegisterAndLoginHandler(t *testing.T) {
+  _ = setupTestRedis(t)
+  handler := setupTestHandler(t)
+
+  register := func(email, password string) *http.Response {
+    payload, _ := json.Marshal(map[string]string{"email": email, "password": password})
+    req := httptest.NewRequest("POST", "/auth/register", bytes.NewBuffer(payload))
+    req.Header.Set("Content-Type", "application/json")
+    w := httptest.NewRecorder()
+    handler.RegisterHandler(w, req)
+    return w.Result()
+  }
+
+  resp := register("alice@example.com", "hunter2")
+  if resp.StatusCode != http.StatusOK {
+    t.Fatalf("Expected status %v on register, got %v", http.StatusOK, resp.StatusCode)
+  }
+  var registerBody map[string]string
+  if err := json.NewDecoder(resp.Body).Decode(&registerBody); err != nil {
+    t.Fatalf("Failed to decode register response: %v", err)
+  }
+  if registerBody["token"] == "" {
+    t.Errorf("Expected a non-empty token, got %v", registerBody)
+  }
+
+  // Registering the same email again must fail
+  resp = register("alice@example.com", "different")
+  if resp.StatusCode != http.StatusConflict {
+    t.Errorf("Expected status %v for a duplicate email, got %v", http.StatusConflict, resp.StatusCode)
+  }
+
+  login := func(email, password string) *http.Response {
+    payload, _ := json.Marshal(map[string]string{"email": email, "password": password})
+    req := httptest.NewRequest("POST", "/auth/login", bytes.NewBuffer(payload))
+    req.Header.Set("Content-Type", "application/json")
+    w := httptest.NewRecorder()
+    handler.LoginHandler(w, req)
+    return w.Result()
+  }
+
+  // Wrong password must fail
+  resp = login("alice@example.com", "wrong")
+  if resp.StatusCode != http.StatusUnauthorized {
+    t.Errorf("Expected status %v for a wrong password, got %v", http.StatusUnauthorized, resp.StatusCode)
+  }
+
+  // Correct password must succeed and return a usable token
+  resp = login("alice@example.com", "hunter2")
+  if resp.StatusCode != http.StatusOK {
+    t.Fatalf("Expected status %v on login, got %v", http.StatusOK, resp.StatusCode)
+  }
+  var loginBody map[string]string
+  if err := json.NewDecoder(resp.Body).Decode(&loginBody); err != nil {
+    t.Fatalf("Failed to decode login response: %v", err)
+  }
+  if loginBody["token"] == "" {
+    t.Errorf("Expected a non-empty token, got %v", loginBody)
+  }
+}
+
+// Test that GenerateShortURL deduplicates per owner: the same user
+// submitting the same URL twice gets back the same short URL, but a
+// different user submitting it gets their own
+func TestGenerateShortURLDedupPerUser(t *testing.T) {
+  _ = setupTestRedis(t)
+  handler := setupTestHandler(t)
+
+  const originalURL = "https://github.com/eliuttth-dev"
+
+  first, err := handler.Shortener.GenerateShortURL(originalURL, "", nil, 1, "", nil)
+  if err != nil {
+    t.Fatalf("Failed to generate short URL: %v", err)
+  }
+
+  second, err := handler.Shortener.GenerateShortURL(originalURL, "", nil, 1, "", nil)
+  if err != nil {
+    t.Fatalf("Failed to generate short URL: %v", err)
+  }
+  if second != first {
+    t.Errorf("Expected the same user resubmitting a URL to get back %v, got %v", first, second)
+  }
+
+  other, err := handler.Shortener.GenerateShortURL(originalURL, "", nil, 2, "", nil)
+  if err != nil {
+    t.Fatalf("Failed to generate short URL: %v", err)
+  }
+  if other == first {
+    t.Errorf("Expected a different owner to get their own short URL, got the same one: %v", other)
+  }
+}
+
+// Test ListLinksHandler and DeleteLinkHandler: a caller only sees and can
+// only delete their own links
+func TestListAndDeleteLinkHandler(t *testing.T) {
+  _ = setupTestRedis(t)
+  handler := setupTestHandler(t)
+
+  ownerID := int64(1)
+  token, err := auth.GenerateToken(ownerID)
+  if err != nil {
+    t.Fatalf("Failed to generate token: %v", err)
+  }
+
+  ownShort, err := handler.Shortener.GenerateShortURL("https://github.com/eliuttth-dev", "eliuth-own", nil, ownerID, "", nil)
+  if err != nil {
+    t.Fatalf("Failed to prepopulate database: %v", err)
+  }
+  if _, err := handler.Shortener.GenerateShortURL("https://example.com", "eliuth-other-owner", nil, 2, "", nil); err != nil {
+    t.Fatalf("Failed to prepopulate database: %v", err)
+  }
+
+  router := mux.NewRouter()
+  router.HandleFunc("/links", middleware.RequireAuth(handler.ListLinksHandler)).Methods("GET")
+  router.HandleFunc("/{shortURL}", middleware.RequireAuth(handler.DeleteLinkHandler)).Methods("DELETE")
+
+  // GET /links without a token must be rejected
+  req := httptest.NewRequest("GET", "/links", nil)
+  w := httptest.NewRecorder()
+  router.ServeHTTP(w, req)
+  if w.Result().StatusCode != http.StatusUnauthorized {
+    t.Errorf("Expected status %v without a token, got %v", http.StatusUnauthorized, w.Result().StatusCode)
+  }
+
+  // GET /links must only return the caller's own links
+  req = httptest.NewRequest("GET", "/links", nil)
+  req.Header.Set("Authorization", "Bearer "+token)
+  w = httptest.NewRecorder()
+  router.ServeHTTP(w, req)
+  if w.Result().StatusCode != http.StatusOK {
+    t.Fatalf("Expected status %v, got %v", http.StatusOK, w.Result().StatusCode)
+  }
+  var links []Link
+  if err := json.NewDecoder(w.Result().Body).Decode(&links); err != nil {
+    t.Fatalf("Failed to decode links response: %v", err)
+  }
+  if len(links) != 1 || links[0].ShortURL != ownShort {
+    t.Errorf("Expected only %v in the caller's links, got %v", ownShort, links)
+  }
+
+  // Deleting someone else's link must fail
+  req = httptest.NewRequest("DELETE", "/eliuth-other-owner", nil)
+  req.Header.Set("Authorization", "Bearer "+token)
+  w = httptest.NewRecorder()
+  router.ServeHTTP(w, req)
+  if w.Result().StatusCode != http.StatusNotFound {
+    t.Errorf("Expected status %v deleting another owner's link, got %v", http.StatusNotFound, w.Result().StatusCode)
+  }
+
+  // Deleting the caller's own link must succeed and soft-delete it
+  req = httptest.NewRequest("DELETE", "/"+ownShort, nil)
+  req.Header.Set("Authorization", "Bearer "+token)
+  w = httptest.NewRecorder()
+  router.ServeHTTP(w, req)
+  if w.Result().StatusCode != http.StatusNoContent {
+    t.Errorf("Expected status %v deleting the caller's own link, got %v", http.StatusNoContent, w.Result().StatusCode)
+  }
+
+  req = httptest.NewRequest("GET", "/links", nil)
+  req.Header.Set("Authorization", "Bearer "+token)
+  w = httptest.NewRecorder()
+  router.ServeHTTP(w, req)
+  links = nil
+  if err := json.NewDecoder(w.Result().Body).Decode(&links); err != nil {
+    t.Fatalf("Failed to decode links response: %v", err)
+  }
+  if len(links) != 0 {
+    t.Errorf("Expected the deleted link to no longer be listed, got %v", links)
+  }
+}
+
+// Test that resolving a short URL records a click and that GET
+// /{shortURL}/stats reflects it. Click recording is asynchronous (queued
+// through a channel and drained by processClickEvents), so this polls the
+// stats endpoint briefly instead of asserting immediately
+func TestRecordClickAndStats(t *testing.T) {
+  _ = setupTestRedis(t)
+  handler := setupTestHandler(t)
+
+  shortURL, err := handler.Shortener.GenerateShortURL("https://github.com/eliuttth-dev", "eliuth-stats", nil, 0, "", nil)
   if err != nil {
-    t.Fatalf("Failed to query expiration time: %v", err)
+    t.Fatalf("Failed to prepopulate database: %v", err)
   }
 
-  if dbExpiration.IsZero() || !dbExpiration.Round(time.Second).Equal(expiration.Round(time.Second)) {
-    t.Errorf("Expected expiration time %v, got %v", expiration, dbExpiration)
+  router := mux.NewRouter()
+  router.HandleFunc("/{shortURL}", handler.ResolveHandler).Methods("GET")
+  router.HandleFunc("/{shortURL}/stats", handler.StatsHandler).Methods("GET")
+
+  req := httptest.NewRequest("GET", "/"+shortURL, nil)
+  req.Header.Set("Referer", "https://example.com")
+  w := httptest.NewRecorder()
+  router.ServeHTTP(w, req)
+  if w.Result().StatusCode != http.StatusFound {
+    t.Fatalf("Expected status %v resolving the short URL, got %v", http.StatusFound, w.Result().StatusCode)
+  }
+
+  var stats ClickStats
+  deadline := time.Now().Add(2 * time.Second)
+  for {
+    req = httptest.NewRequest("GET", "/"+shortURL+"/stats", nil)
+    w = httptest.NewRecorder()
+    router.ServeHTTP(w, req)
+
+    if w.Result().StatusCode != http.StatusOK {
+      t.Fatalf("Expected status %v from stats, got %v", http.StatusOK, w.Result().StatusCode)
+    }
+    if err := json.NewDecoder(w.Result().Body).Decode(&stats); err != nil {
+      t.Fatalf("Failed to decode stats response: %v", err)
+    }
+
+    if stats.TotalClicks >= 1 || time.Now().After(deadline) {
+      break
+    }
+    time.Sleep(10 * time.Millisecond)
+  }
+
+  if stats.TotalClicks != 1 {
+    t.Errorf("Expected 1 total click, got %v", stats.TotalClicks)
   }
+  if stats.UniqueVisitors != 1 {
+    t.Errorf("Expected 1 unique visitor, got %v", stats.UniqueVisitors)
+  }
+}
+
+// Benchmarks concurrent GenerateShortURL calls, demonstrating that writes no
+// longer serialize through a process-wide mutex now that ids come from an
+// atomic Redis INCR instead of a `SELECT MAX(id)+1` scan
+func BenchmarkGenerateShortURLConcurrent(b *testing.B) {
+  setupTestRedis(b)
+  handler := setupTestHandler(b)
+
+  b.ResetTimer()
+  b.RunParallel(func(pb *testing.PB) {
+    for pb.Next() {
+      if _, err := handler.Shortener.GenerateShortURL("https://github.com/eliuttth-dev", "", nil, 0, "", nil); err != nil {
+        b.Fatalf("GenerateShortURL failed: %v", err)
+      }
+    }
+  })
 }
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+  "encoding/json"
+  "net/http"
+
+  "github.com/gorilla/mux"
+
+  "go-url-shortener/internal/middleware"
+)
+
+// Handles request to list the caller's short URLs
+func (h *URLShortenerHandler) ListLinksHandler(w http.ResponseWriter, r *http.Request) {
+  userID, ok := middleware.UserIDFromContext(r.Context())
+  if !ok {
+    http.Error(w, "Missing or invalid Authorization header", http.StatusUnauthorized)
+    return
+  }
+
+  links, err := h.Shortener.ListLinksByUser(userID)
+  if err != nil {
+    http.Error(w, "Failed to list links", http.StatusInternalServerError)
+    return
+  }
+
+  w.Header().Set("Content-Type", "application/json")
+  json.NewEncoder(w).Encode(links)
+}
+
+// Handles request to soft-delete a short URL owned by the caller
+func (h *URLShortenerHandler) DeleteLinkHandler(w http.ResponseWriter, r *http.Request) {
+  userID, ok := middleware.UserIDFromContext(r.Context())
+  if !ok {
+    http.Error(w, "Missing or invalid Authorization header", http.StatusUnauthorized)
+    return
+  }
+
+  vars := mux.Vars(r)
+  shortURL := vars["shortURL"]
+  if shortURL == "" {
+    http.Error(w, "Invalid request: 'shortURL' cannot be empty", http.StatusBadRequest)
+    return
+  }
+
+  if err := h.Shortener.DeleteShortURL(userID, shortURL); err != nil {
+    http.Error(w, err.Error(), http.StatusNotFound)
+    return
+  }
+
+  w.WriteHeader(http.StatusNoContent)
+}
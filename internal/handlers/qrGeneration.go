@@ -0,0 +1,227 @@
+package handlers
+
+import (
+  "context"
+  "crypto/sha256"
+  "encoding/hex"
+  "errors"
+  "fmt"
+  "image/color"
+  "log"
+  "net/http"
+  "strconv"
+  "strings"
+  "time"
+
+  "github.com/gorilla/mux"
+  "github.com/skip2/go-qrcode"
+)
+
+// How long generated QR codes are cached in Redis before they're regenerated
+const qrCacheTTL = 24 * time.Hour
+
+// Default side length, in pixels, of a generated QR code
+const defaultQRSize = 256
+
+// Largest side length, in pixels, a caller may request. qr.PNG/Image
+// allocates a size*size paletted image with no internal cap, so an
+// unbounded ?size= is an unauthenticated memory-exhaustion vector
+const maxQRSize = 1024
+
+// Query params accepted by QRCodeHandler
+type qrOptions struct {
+  Format     string // "png" or "svg"
+  Size       int
+  Level      qrcode.RecoveryLevel
+  Foreground string // hex color, e.g. "000000"
+  Background string // hex color, e.g. "ffffff"
+}
+
+// Handles request to render a QR code encoding a short link
+func (h *URLShortenerHandler) QRCodeHandler(w http.ResponseWriter, r *http.Request) {
+  vars := mux.Vars(r)
+  shortURL := vars["shortURL"]
+  if shortURL == "" {
+    http.Error(w, "Invalid request: 'shortURL' cannot be empty", http.StatusBadRequest)
+    return
+  }
+
+  // Reuse the resolver to make sure the short code actually exists before rendering
+  if _, exists := h.Shortener.resolveRecord(shortURL); !exists {
+    http.Error(w, "Short URL not found: No record exists for the given 'shortURL'", http.StatusNotFound)
+    return
+  }
+
+  opts, err := parseQROptions(r)
+  if err != nil {
+    http.Error(w, err.Error(), http.StatusBadRequest)
+    return
+  }
+
+  link := fullyQualifiedShortLink(r, shortURL)
+  image, contentType, err := h.Shortener.renderQRCode(shortURL, link, opts)
+  if err != nil {
+    http.Error(w, fmt.Sprintf("Failed to generate QR code: %v", err), http.StatusInternalServerError)
+    return
+  }
+
+  w.Header().Set("Content-Type", contentType)
+  w.Write(image)
+}
+
+// Parses and validates the ?format=/?size=/?ecc=/?fg=/?bg= query params,
+// falling back to sensible defaults when they're omitted
+func parseQROptions(r *http.Request) (qrOptions, error) {
+  q := r.URL.Query()
+
+  format := strings.ToLower(q.Get("format"))
+  if format == "" {
+    format = "png"
+  }
+  if format != "png" && format != "svg" {
+    return qrOptions{}, errors.New("Invalid 'format': must be 'png' or 'svg'")
+  }
+
+  size := defaultQRSize
+  if raw := q.Get("size"); raw != "" {
+    parsed, err := strconv.Atoi(raw)
+    if err != nil || parsed <= 0 || parsed > maxQRSize {
+      return qrOptions{}, fmt.Errorf("Invalid 'size': must be a positive integer up to %d", maxQRSize)
+    }
+    size = parsed
+  }
+
+  level := qrcode.Medium
+  if raw := strings.ToUpper(q.Get("ecc")); raw != "" {
+    switch raw {
+    case "L":
+      level = qrcode.Low
+    case "M":
+      level = qrcode.Medium
+    case "Q":
+      level = qrcode.High
+    case "H":
+      level = qrcode.Highest
+    default:
+      return qrOptions{}, errors.New("Invalid 'ecc': must be one of 'L', 'M', 'Q', 'H'")
+    }
+  }
+
+  foreground := q.Get("fg")
+  if foreground == "" {
+    foreground = "000000"
+  }
+  background := q.Get("bg")
+  if background == "" {
+    background = "ffffff"
+  }
+  if _, err := parseHexColor(foreground); err != nil {
+    return qrOptions{}, fmt.Errorf("Invalid 'fg': %v", err)
+  }
+  if _, err := parseHexColor(background); err != nil {
+    return qrOptions{}, fmt.Errorf("Invalid 'bg': %v", err)
+  }
+
+  return qrOptions{Format: format, Size: size, Level: level, Foreground: foreground, Background: background}, nil
+}
+
+// Renders (or fetches from the Redis cache) the QR code image for shortURL
+// encoding link, returning the image bytes alongside the matching Content-Type
+func (us *URLShortener) renderQRCode(shortURL, link string, opts qrOptions) ([]byte, string, error) {
+  ctx := context.Background()
+
+  contentType := "image/png"
+  if opts.Format == "svg" {
+    contentType = "image/svg+xml"
+  }
+
+  cacheKey := qrCacheKey(shortURL, opts)
+  if cached, err := us.cache.Get(ctx, cacheKey).Bytes(); err == nil {
+    return cached, contentType, nil
+  }
+
+  foreground, err := parseHexColor(opts.Foreground)
+  if err != nil {
+    return nil, "", err
+  }
+  background, err := parseHexColor(opts.Background)
+  if err != nil {
+    return nil, "", err
+  }
+
+  qr, err := qrcode.New(link, opts.Level)
+  if err != nil {
+    return nil, "", fmt.Errorf("Failed to encode QR code: %v", err)
+  }
+  qr.ForegroundColor = foreground
+  qr.BackgroundColor = background
+
+  var image []byte
+  if opts.Format == "svg" {
+    image = renderQRSVG(qr, opts.Size, foreground, background)
+  } else {
+    image, err = qr.PNG(opts.Size)
+    if err != nil {
+      return nil, "", fmt.Errorf("Failed to render PNG: %v", err)
+    }
+  }
+
+  if err := us.cache.Set(ctx, cacheKey, image, qrCacheTTL).Err(); err != nil {
+    log.Printf("[Redis] Failed to cache QR code for %s: %v", shortURL, err)
+  }
+
+  return image, contentType, nil
+}
+
+// Builds the Redis key a QR code is cached under, mirroring the existing
+// short-URL cache pattern. The params hash keeps distinct format/size/ecc/color
+// combinations for the same short URL from colliding on the same key
+func qrCacheKey(shortURL string, opts qrOptions) string {
+  params := fmt.Sprintf("%s|%d|%d|%s|%s", opts.Format, opts.Size, opts.Level, opts.Foreground, opts.Background)
+  sum := sha256.Sum256([]byte(params))
+  return "qr:" + shortURL + ":" + hex.EncodeToString(sum[:])
+}
+
+// Renders a QR code's bitmap as a minimal SVG, since go-qrcode only emits
+// PNG/terminal output natively
+func renderQRSVG(qr *qrcode.QRCode, size int, foreground, background color.RGBA) []byte {
+  bitmap := qr.Bitmap()
+  if size <= 0 {
+    size = defaultQRSize
+  }
+  cell := float64(size) / float64(len(bitmap))
+
+  var svg strings.Builder
+  fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, size, size, size, size)
+  fmt.Fprintf(&svg, `<rect width="100%%" height="100%%" fill="#%02x%02x%02x"/>`, background.R, background.G, background.B)
+  for y, row := range bitmap {
+    for x, dark := range row {
+      if !dark {
+        continue
+      }
+      fmt.Fprintf(&svg, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="#%02x%02x%02x"/>`, float64(x)*cell, float64(y)*cell, cell, cell, foreground.R, foreground.G, foreground.B)
+    }
+  }
+  svg.WriteString(`</svg>`)
+
+  return []byte(svg.String())
+}
+
+// Parses a 6-digit hex color (with or without a leading '#') into an RGBA value
+func parseHexColor(s string) (color.RGBA, error) {
+  s = strings.TrimPrefix(s, "#")
+  raw, err := hex.DecodeString(s)
+  if err != nil || len(raw) != 3 {
+    return color.RGBA{}, fmt.Errorf("Invalid color %q: expected 6 hex digits", s)
+  }
+  return color.RGBA{R: raw[0], G: raw[1], B: raw[2], A: 0xff}, nil
+}
+
+// Builds the fully-qualified short link a QR code should encode
+func fullyQualifiedShortLink(r *http.Request, shortURL string) string {
+  scheme := "http"
+  if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+    scheme = "https"
+  }
+  return fmt.Sprintf("%s://%s/%s", scheme, r.Host, shortURL)
+}
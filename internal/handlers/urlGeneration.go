@@ -5,7 +5,6 @@ import (
   "database/sql"
   "encoding/json"
   "net/http"
-  "sync"
   "log"
   "fmt"
   "time"
@@ -13,47 +12,62 @@ import (
   "errors"
   "github.com/redis/go-redis/v9"
   "github.com/gorilla/mux"
-  _ "github.com/mattn/go-sqlite3"
+
+  "go-url-shortener/internal/auth"
+  "go-url-shortener/internal/middleware"
+  "go-url-shortener/internal/storage"
 )
 
 // Manage the URL shortening and resolution
 type URLShortener struct {
+  store storage.Storage
+  // Backs the users/clicks/click_stats tables, which aren't part of the
+  // Storage abstraction yet, so we keep the raw connection around for them
   db *sql.DB
+  // The driver backing db ("sqlite", "postgres", or "memory"), so raw
+  // queries against the aux tables can pick the right placeholder style
+  // and dialect-specific expressions
+  driver string
   cache *redis.Client
-  mu sync.Mutex
+  clickEvents chan ClickEvent
 }
 
+// Redis key backing the monotonic counter used to mint short URL ids,
+// shared across replicas so id generation never depends on a single
+// instance's in-process state
+const shortlinkCountKey = "shortlinkCount"
+
 // Handles HTTP request related to URL shortening and resolution
 type URLShortenerHandler struct {
   Shortener *URLShortener
 }
 
-//  Initializes the URLShortener instance, setting up the SQLite database
-//  and Redis client. It also ensures the necessary database table exists
+// Represents a short URL owned by a user, as returned by GET /links
+type Link struct {
+  ShortURL       string     `json:"short_url"`
+  OriginalURL    string     `json:"original_url"`
+  ExpirationTime *time.Time `json:"expiration_time,omitempty"`
+}
+
+//  Initializes the URLShortener instance, opening the urls Storage backend
+//  named by driver and setting up the Redis client. It also ensures the
+//  auxiliary database tables exist
 //
 //  Parameters:
-//    - dbPath: The path to the SQlite database file
+//    - driver: The Storage backend to use ("sqlite", "postgres", or "memory")
+//    - dsn: The file path (sqlite) or connection string (postgres) to open
 //    - redisAddr: the address of the Redis server
 //
 //  Returns:
 //    - A pointer to the URLShortener instance
 //    - An error if the database or Redis initialization fails
-func NewURLGeneration(dbPath string, redisAddr string) (*URLShortener, error) {
-  db, err := sql.Open("sqlite3", dbPath)
+func NewURLGeneration(driver string, dsn string, redisAddr string) (*URLShortener, error) {
+  store, db, err := storage.Open(driver, dsn)
   if err != nil {
     return nil, err
   }
 
-  // Create table if it doesn't exist
-  createTableQuery := `
-  CREATE TABLE IF NOT EXISTS urls (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    short_url TEXT NOT NULL UNIQUE,
-    original_url TEXT NOT NULL,
-    expiration_time TIMESTAMP NULL
-  );`
-  _, err = db.Exec(createTableQuery)
-  if err != nil {
+  if err := ensureAuxTables(db, driver); err != nil {
     return nil, err
   }
 
@@ -68,15 +82,107 @@ func NewURLGeneration(dbPath string, redisAddr string) (*URLShortener, error) {
   }
 
   urlShortener := &URLShortener{
+    store: store,
     db: db,
+    driver: driver,
     cache: cache,
+    clickEvents: make(chan ClickEvent, clickEventBufferSize),
+  }
+
+  if err := urlShortener.seedShortURLCounter(context.Background()); err != nil {
+    return nil, fmt.Errorf("Failed to seed short URL id counter: %v", err)
   }
 
   go urlShortener.DeleteExpiredURLs()
+  go urlShortener.processClickEvents()
+  go urlShortener.flushClickCounters()
 
   return urlShortener, nil
 }
 
+// Creates the users/clicks/click_stats tables, which sit outside the
+// Storage abstraction since it only covers the urls table
+func ensureAuxTables(db *sql.DB, driver string) error {
+  idColumn := "INTEGER PRIMARY KEY AUTOINCREMENT"
+  if driver == "postgres" {
+    idColumn = "SERIAL PRIMARY KEY"
+  }
+
+  createUsersTableQuery := fmt.Sprintf(`
+  CREATE TABLE IF NOT EXISTS users (
+    id %s,
+    email TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    api_token TEXT NOT NULL UNIQUE
+  );`, idColumn)
+  if _, err := db.Exec(createUsersTableQuery); err != nil {
+    return err
+  }
+
+  createClicksTableQuery := fmt.Sprintf(`
+  CREATE TABLE IF NOT EXISTS clicks (
+    id %s,
+    short_url TEXT NOT NULL,
+    timestamp TIMESTAMP NOT NULL,
+    ip_hash TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    referer TEXT NOT NULL,
+    country TEXT NOT NULL
+  );`, idColumn)
+  if _, err := db.Exec(createClicksTableQuery); err != nil {
+    return err
+  }
+
+  createClickStatsTableQuery := `
+  CREATE TABLE IF NOT EXISTS click_stats (
+    short_url TEXT PRIMARY KEY,
+    total_clicks INTEGER NOT NULL DEFAULT 0,
+    unique_visitors INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP
+  );`
+  _, err := db.Exec(createClickStatsTableQuery)
+  return err
+}
+
+// Returns the SQL placeholder for the n-th (1-indexed) parameter in a raw
+// query against the aux tables, matching whichever driver ensureAuxTables
+// created them under ("?" for sqlite/memory, "$n" for postgres)
+func (us *URLShortener) placeholder(n int) string {
+  if us.driver == "postgres" {
+    return fmt.Sprintf("$%d", n)
+  }
+  return "?"
+}
+
+// Returns n comma-joined positional placeholders, for building raw INSERT
+// statements against the aux tables
+func (us *URLShortener) placeholders(n int) string {
+  parts := make([]string, n)
+  for i := range parts {
+    parts[i] = us.placeholder(i + 1)
+  }
+  return strings.Join(parts, ", ")
+}
+
+// Inserts a new user row, returning its id. lib/pq doesn't support
+// LastInsertId, so postgres uses RETURNING id instead of sqlite/memory's
+// Exec + LastInsertId path
+func (us *URLShortener) insertUser(email, passwordHash, apiToken string) (int64, error) {
+  if us.driver == "postgres" {
+    query := fmt.Sprintf(`INSERT INTO users (email, password_hash, api_token) VALUES (%s) RETURNING id`, us.placeholders(3))
+    var userID int64
+    err := us.db.QueryRow(query, email, passwordHash, apiToken).Scan(&userID)
+    return userID, err
+  }
+
+  query := fmt.Sprintf(`INSERT INTO users (email, password_hash, api_token) VALUES (%s)`, us.placeholders(3))
+  result, err := us.db.Exec(query, email, passwordHash, apiToken)
+  if err != nil {
+    return 0, err
+  }
+  return result.LastInsertId()
+}
+
 // CHANGE THIS TO A SEPARATE UTIL FILE
 func isValidCustomURL(customURL string) bool {
   const validChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-_"
@@ -88,31 +194,39 @@ func isValidCustomURL(customURL string) bool {
   return true
 }
 
-// Generates a unique short URL
-func (us *URLShortener) GenerateShortURL(originalURL string, customShortURL string, expirationTime *time.Time) (string, error) {
-  us.mu.Lock()
-  defer us.mu.Unlock()
+// Generates a unique short URL, scoped to userID (0 means no owner).
+// passwordHash, when non-empty, gates resolution behind POST /{shortURL}/unlock.
+// maxUses, when non-nil, caps how many times the link may be resolved/unlocked
+func (us *URLShortener) GenerateShortURL(originalURL string, customShortURL string, expirationTime *time.Time, userID int64, passwordHash string, maxUses *int64) (string, error) {
+  ctx := context.Background()
 
-  if customShortURL != "" {
-    if !isValidCustomURL(customShortURL) {
-      return "", errors.New("Invalid characters in custom short URL")
-    }
-    
-    // Check if custom short URL already exits
-    var exists bool
-    query := `SELECT EXISTS(SELECT 1 FROM urls WHERE short_url = ?)`
-    err := us.db.QueryRow(query, customShortURL).Scan(&exists)
+  // A user re-submitting a URL they already own gets back their existing
+  // short URL, unless they're asking to lock it down with a password/max
+  // uses now — silently handing back the old, unprotected link would
+  // discard that request instead of applying it
+  if userID != 0 {
+    existing, ok, err := us.store.FindByOwner(ctx, originalURL, userID)
     if err != nil {
       return "", fmt.Errorf("Database error: %v", err)
     }
-    if exists {
-      return "", fmt.Errorf("Custom short URL already exists")
+    if ok {
+      if passwordHash != "" || maxUses != nil {
+        return "", fmt.Errorf("A short URL already exists for this owner: %s", existing.ShortURL)
+      }
+      return existing.ShortURL, nil
     }
+  }
 
-    // Insert the ustom short URL into the database
-    insertQuery := `INSERT INTO urls (short_url, original_url, expiration_time) VALUES (?, ?, ?)`
-    _, err = us.db.Exec(insertQuery, customShortURL, originalURL, expirationTime)
-    if err != nil {
+  if customShortURL != "" {
+    if !isValidCustomURL(customShortURL) {
+      return "", errors.New("Invalid characters in custom short URL")
+    }
+
+    record := storage.URLRecord{ShortURL: customShortURL, OriginalURL: originalURL, ExpirationTime: expirationTime, UserID: userID, PasswordHash: passwordHash, MaxUses: maxUses}
+    if err := us.store.Put(ctx, record); err != nil {
+      if errors.Is(err, storage.ErrShortURLExists) {
+        return "", fmt.Errorf("Custom short URL already exists")
+      }
       return "", fmt.Errorf("Failed to store custom short URL: %v", err)
     }
 
@@ -120,99 +234,182 @@ func (us *URLShortener) GenerateShortURL(originalURL string, customShortURL stri
   }
 
   // Generate a unique ID for the short URL
-  var id int64
-  query := `SELECT COALESCE(MAX(id), 0) + 1 FROM urls`
-  err := us.db.QueryRow(query).Scan(&id)
+  id, err := us.nextShortURLID(ctx)
   if err != nil {
-      return "", err
+    return "", err
   }
 
   shortURL := encodeBase62(id)
 
-  // Insert the record with the short URL and original URL
-  insertQuery := `INSERT INTO urls (id, short_url, original_url, expiration_time) VALUES (?, ?, ?, ?)`
-  _, err = us.db.Exec(insertQuery, id, shortURL, originalURL, expirationTime)
-  if err != nil {
-      return "", err
+  record := storage.URLRecord{ShortURL: shortURL, OriginalURL: originalURL, ExpirationTime: expirationTime, UserID: userID, PasswordHash: passwordHash, MaxUses: maxUses}
+  if err := us.store.Put(ctx, record); err != nil {
+    return "", err
   }
 
   return shortURL, nil
 }
 
+// Atomically allocates the next short URL id off the shared Redis counter.
+// This replaces the old `SELECT MAX(id)+1` pattern, which required a
+// process-wide mutex to stay race-free and serialized every write; INCR is
+// atomic on its own and the counter is shared across replicas, so writes
+// across instances never collide on the same id
+func (us *URLShortener) nextShortURLID(ctx context.Context) (int64, error) {
+  id, err := us.cache.Incr(ctx, shortlinkCountKey).Result()
+  if err != nil {
+    return 0, fmt.Errorf("Failed to allocate short URL id: %v", err)
+  }
+  return id, nil
+}
+
+// Ensures the Redis id counter is at least as high as the largest id
+// already encoded in a stored short URL, so a Redis restart/flush (it's
+// treated as disposable cache elsewhere) between process starts doesn't
+// reset the counter back into a range of ids that are already taken. It
+// runs once, from NewURLGeneration; a Redis flush while the process is
+// running is not detected
+func (us *URLShortener) seedShortURLCounter(ctx context.Context) error {
+  maxID, err := us.store.MaxShortURLID(ctx)
+  if err != nil {
+    return fmt.Errorf("Failed to read max short URL id from storage: %v", err)
+  }
+
+  current, err := us.cache.Get(ctx, shortlinkCountKey).Int64()
+  if err != nil && err != redis.Nil {
+    return fmt.Errorf("Failed to read short URL id counter: %v", err)
+  }
+
+  if current < maxID {
+    if err := us.cache.Set(ctx, shortlinkCountKey, maxID, 0).Err(); err != nil {
+      return fmt.Errorf("Failed to seed short URL id counter: %v", err)
+    }
+  }
+
+  return nil
+}
+
+// Lists the short URLs owned by userID, excluding soft-deleted links
+func (us *URLShortener) ListLinksByUser(userID int64) ([]Link, error) {
+  records, err := us.store.ListByUser(context.Background(), userID)
+  if err != nil {
+    return nil, fmt.Errorf("Database error: %v", err)
+  }
+
+  links := make([]Link, 0, len(records))
+  for _, record := range records {
+    links = append(links, Link{ShortURL: record.ShortURL, OriginalURL: record.OriginalURL, ExpirationTime: record.ExpirationTime})
+  }
+
+  return links, nil
+}
+
+// Soft-deletes a short URL owned by userID, also evicting it from the Redis cache
+func (us *URLShortener) DeleteShortURL(userID int64, shortURL string) error {
+  ok, err := us.store.SoftDelete(context.Background(), userID, shortURL)
+  if err != nil {
+    return fmt.Errorf("Database error: %v", err)
+  }
+  if !ok {
+    return errors.New("Short URL not found or not owned by caller")
+  }
+
+  if err := us.cache.Del(context.Background(), shortURL).Err(); err != nil {
+    log.Printf("[Redis] Failed to evict deleted short URL: %s, error: %v", shortURL, err)
+  }
+
+  return nil
+}
+
 // Resolves a short URL back to the original URL
 func (us *URLShortener) ResolveShortURL(shortURL string) (string, bool) {
-  ctx := context.Background()
-
-  // Check Redis cache first
-  cachedURL, err := us.cache.Get(ctx, shortURL).Result()
-  if err == nil {
-    log.Printf("[Redis] Cache hit: %s --> %s", shortURL, cachedURL)
+  if cachedURL, hit := us.cachedOriginalURL(shortURL); hit {
     return cachedURL, true
-  } else if err != redis.Nil { // redis.Nil means key does not exist
-    log.Printf("[Redis] Cache error for %s: %v", shortURL, err)
   }
 
   log.Printf("[Redis] Cache miss: %s", shortURL)
 
-  // Fallback to database lookup
-  us.mu.Lock()
-  defer us.mu.Unlock()
-
-  query := `SELECT original_url FROM urls WHERE short_url = ?`
-  var originalURL string
-  err = us.db.QueryRow(query, shortURL).Scan(&originalURL)
+  // Fallback to the storage backend
+  record, err := us.store.Get(context.Background(), shortURL)
   if err != nil {
     log.Printf("[DB] Short URL not found: %s", shortURL)
     return "", false
   }
 
-  // Add result to Redis cache
-  err = us.cache.Set(ctx, shortURL, originalURL, 24*time.Hour).Err()
-  if err != nil {
+  // Password-protected and limited-use links must go through resolveRecord
+  // so callers can enforce those checks; caching the bare original URL here
+  // would let a cache hit bypass them entirely
+  if record.PasswordHash == "" && record.MaxUses == nil {
+    us.cacheOriginalURL(shortURL, record.OriginalURL)
+  }
+
+  return record.OriginalURL, true
+}
+
+// Looks up a short URL in the Redis cache only, without falling back to the
+// storage backend on a miss
+func (us *URLShortener) cachedOriginalURL(shortURL string) (string, bool) {
+  cachedURL, err := us.cache.Get(context.Background(), shortURL).Result()
+  if err == nil {
+    log.Printf("[Redis] Cache hit: %s --> %s", shortURL, cachedURL)
+    return cachedURL, true
+  }
+  if err != redis.Nil { // redis.Nil means key does not exist
+    log.Printf("[Redis] Cache error for %s: %v", shortURL, err)
+  }
+  return "", false
+}
+
+// Caches a short URL's original URL for 24h. Only ever called for links with
+// no password/max-uses, so a cache hit is always safe to redirect from directly
+func (us *URLShortener) cacheOriginalURL(shortURL, originalURL string) {
+  if err := us.cache.Set(context.Background(), shortURL, originalURL, 24*time.Hour).Err(); err != nil {
     log.Printf("[Redis] Failed to cache short URL: %s -> %s, error: %v", shortURL, originalURL, err)
   } else {
     log.Printf("[Redis] Cached short URL: %s -> %s", shortURL, originalURL)
   }
+}
 
-  return originalURL, true
+// Resolves a short URL to its full record, bypassing the Redis cache so
+// ResolveHandler/UnlockHandler always see the current password/use-count state
+func (us *URLShortener) resolveRecord(shortURL string) (storage.URLRecord, bool) {
+  record, err := us.store.Get(context.Background(), shortURL)
+  if err != nil {
+    return storage.URLRecord{}, false
+  }
+  return record, true
+}
+
+// Atomically consumes one use of a limited-use short URL, reporting whether
+// the use was granted
+func (us *URLShortener) recordUse(shortURL string) (bool, error) {
+  ok, err := us.store.RecordUse(context.Background(), shortURL)
+  if err != nil {
+    return false, fmt.Errorf("Database error: %v", err)
+  }
+  return ok, nil
 }
 
 // Delete Expired URLS
 func (us *URLShortener) DeleteExpiredURLs() {
-    ticker := time.NewTicker(1 * time.Hour) 
+    ticker := time.NewTicker(1 * time.Hour)
     defer ticker.Stop()
 
     for {
         <-ticker.C
-        us.mu.Lock()
-
-        // Query to delete expired URLs from the database
-        query := `DELETE FROM urls WHERE expiration_time IS NOT NULL AND expiration_time < ?`
-        _, err := us.db.Exec(query, time.Now())
-        if err != nil {
-            log.Printf("Failed to delete expired URLs from DB: %v", err)
-        }
 
-        // Clean up Redis
         ctx := context.Background()
-        rows, err := us.db.Query(`SELECT short_url FROM urls WHERE expiration_time IS NOT NULL AND expiration_time < ?`, time.Now())
+        expired, err := us.store.DeleteExpired(ctx, time.Now())
         if err != nil {
-            log.Printf("Failed to query expired URLs for Redis cleanup: %v", err)
-            us.mu.Unlock()
+            log.Printf("Failed to delete expired URLs from DB: %v", err)
             continue
         }
 
-        var shortURL string
-        for rows.Next() {
-            if err := rows.Scan(&shortURL); err == nil {
-                if err := us.cache.Del(ctx, shortURL).Err(); err != nil {
-                    log.Printf("Failed to delete expired short URL from Redis: %s, error: %v", shortURL, err)
-                }
+        // Clean up Redis
+        for _, shortURL := range expired {
+            if err := us.cache.Del(ctx, shortURL).Err(); err != nil {
+                log.Printf("Failed to delete expired short URL from Redis: %s, error: %v", shortURL, err)
             }
         }
-        rows.Close()
-
-        us.mu.Unlock()
     }
 }
 
@@ -232,8 +429,8 @@ func encodeBase62(num int64) string {
 }
 
 // Handles request related to URL shortening
-func NewURLShortenerHandler(dbPath, redisAddr string) (*URLShortenerHandler, error) {
-  shortener, err := NewURLGeneration(dbPath, redisAddr)
+func NewURLShortenerHandler(driver, dsn, redisAddr string) (*URLShortenerHandler, error) {
+  shortener, err := NewURLGeneration(driver, dsn, redisAddr)
   if err != nil {
     return nil, err
   }
@@ -249,8 +446,10 @@ func (h *URLShortenerHandler) GenerateHandler(w http.ResponseWriter, r *http.Req
     OriginalURL    string `json:"original_url"`
     CustomShortURL string `json:"custom_short_url,omitempty"`
     ExpirationTime string `json:"expiration_time,omitempty"`
+    Password       string `json:"password,omitempty"`
+    MaxUses        *int64 `json:"max_uses,omitempty"`
   }
-  
+
   // Decode JSON Body
   if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
     http.Error(w, "Invalid request body: Please provide a valid JSON payload", http.StatusBadRequest)
@@ -273,8 +472,25 @@ func (h *URLShortenerHandler) GenerateHandler(w http.ResponseWriter, r *http.Req
     expirationTime = &parsedTime
   }
 
+  if body.MaxUses != nil && *body.MaxUses <= 0 {
+    http.Error(w, "Invalid 'max_uses': must be a positive integer", http.StatusBadRequest)
+    return
+  }
+
+  var passwordHash string
+  if body.Password != "" {
+    hash, err := auth.HashPassword(body.Password)
+    if err != nil {
+      http.Error(w, "Failed to process password", http.StatusInternalServerError)
+      return
+    }
+    passwordHash = hash
+  }
+
+  userID, _ := middleware.UserIDFromContext(r.Context())
+
   // Generate the short URL
-  shortURL, err := h.Shortener.GenerateShortURL(body.OriginalURL, body.CustomShortURL, expirationTime)
+  shortURL, err := h.Shortener.GenerateShortURL(body.OriginalURL, body.CustomShortURL, expirationTime, userID, passwordHash, body.MaxUses)
   if err != nil {
     http.Error(w, fmt.Sprintf("Failed to generate short URL: %v", err), http.StatusBadRequest)
     return
@@ -292,21 +508,148 @@ func (h *URLShortenerHandler) GenerateHandler(w http.ResponseWriter, r *http.Req
 func (h *URLShortenerHandler) ResolveHandler(w http.ResponseWriter, r *http.Request) {
   vars := mux.Vars(r)
   shortURL := vars["shortURL"]
-  
+
   // Check if short URL is empty
   if shortURL == "" {
     http.Error(w, "Invalid request: 'shortURL' cannot be empty", http.StatusBadRequest)
     return
   }
 
-  // Resolve the short URL
-  originalURL, exists := h.Shortener.ResolveShortURL(shortURL)
+  // A cache hit only ever exists for a link with no password/max-uses (see
+  // ResolveShortURL), so it's always safe to redirect straight from it,
+  // skipping the DB round-trip for the common case
+  if originalURL, hit := h.Shortener.cachedOriginalURL(shortURL); hit {
+    h.Shortener.recordClick(shortURL, r)
+    http.Redirect(w, r, originalURL, http.StatusFound)
+    return
+  }
+
+  // Cache miss: fetch the full record so password/max-uses can be enforced
+  record, exists := h.Shortener.resolveRecord(shortURL)
   if !exists {
     http.Error(w, "Short URL not found: No record exists for the given 'shortURL'", http.StatusNotFound)
     return
   }
 
+  // Password-protected links only redirect via POST /{shortURL}/unlock
+  if record.PasswordHash != "" {
+    writePasswordRequired(w, r, shortURL)
+    return
+  }
+
+  if record.MaxUses != nil {
+    ok, err := h.Shortener.recordUse(shortURL)
+    if err != nil {
+      http.Error(w, err.Error(), http.StatusInternalServerError)
+      return
+    }
+    if !ok {
+      http.Error(w, "This short URL has reached its maximum number of uses", http.StatusGone)
+      return
+    }
+  } else {
+    h.Shortener.cacheOriginalURL(shortURL, record.OriginalURL)
+  }
+
+  // Record the click asynchronously so it never adds latency to the redirect path
+  h.Shortener.recordClick(shortURL, r)
+
   // Redirect to the original URL
-  http.Redirect(w, r, originalURL, http.StatusFound)
+  http.Redirect(w, r, record.OriginalURL, http.StatusFound)
+}
+
+// HTML form presented to browsers hitting a password-protected short URL;
+// it posts straight to the matching /unlock route
+const passwordFormHTML = `<!DOCTYPE html>
+<html>
+<head><title>Password required</title></head>
+<body>
+  <form method="POST" action="/%s/unlock">
+    <label>Password: <input type="password" name="password" autofocus></label>
+    <button type="submit">Unlock</button>
+  </form>
+</body>
+</html>
+`
+
+// Responds 401 with an HTML password form for browsers (Accept: text/html)
+// or a JSON error for API callers
+func writePasswordRequired(w http.ResponseWriter, r *http.Request, shortURL string) {
+  if strings.Contains(r.Header.Get("Accept"), "text/html") {
+    w.Header().Set("Content-Type", "text/html; charset=utf-8")
+    w.WriteHeader(http.StatusUnauthorized)
+    fmt.Fprintf(w, passwordFormHTML, shortURL)
+    return
+  }
+
+  w.Header().Set("Content-Type", "application/json")
+  w.WriteHeader(http.StatusUnauthorized)
+  json.NewEncoder(w).Encode(map[string]string{
+    "error":      "This short URL is password-protected",
+    "unlock_url": "/" + shortURL + "/unlock",
+  })
+}
+
+// Handles request to unlock a password-protected short URL, redirecting to
+// the original URL once the correct password is supplied
+func (h *URLShortenerHandler) UnlockHandler(w http.ResponseWriter, r *http.Request) {
+  vars := mux.Vars(r)
+  shortURL := vars["shortURL"]
+
+  if shortURL == "" {
+    http.Error(w, "Invalid request: 'shortURL' cannot be empty", http.StatusBadRequest)
+    return
+  }
+
+  password, err := passwordFromRequest(r)
+  if err != nil {
+    http.Error(w, "Invalid request body: Please provide a password", http.StatusBadRequest)
+    return
+  }
+
+  record, exists := h.Shortener.resolveRecord(shortURL)
+  if !exists {
+    http.Error(w, "Short URL not found: No record exists for the given 'shortURL'", http.StatusNotFound)
+    return
+  }
+
+  if record.PasswordHash == "" || !auth.CheckPassword(record.PasswordHash, password) {
+    http.Error(w, "Invalid password", http.StatusUnauthorized)
+    return
+  }
+
+  if record.MaxUses != nil {
+    ok, err := h.Shortener.recordUse(shortURL)
+    if err != nil {
+      http.Error(w, err.Error(), http.StatusInternalServerError)
+      return
+    }
+    if !ok {
+      http.Error(w, "This short URL has reached its maximum number of uses", http.StatusGone)
+      return
+    }
+  }
+
+  h.Shortener.recordClick(shortURL, r)
+  http.Redirect(w, r, record.OriginalURL, http.StatusFound)
+}
+
+// Reads the submitted password from a JSON body ({"password": "..."}) or an
+// HTML form submission, matching whichever content type the client sent
+func passwordFromRequest(r *http.Request) (string, error) {
+  if strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+    var body struct {
+      Password string `json:"password"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+      return "", err
+    }
+    return body.Password, nil
+  }
+
+  if err := r.ParseForm(); err != nil {
+    return "", err
+  }
+  return r.FormValue("password"), nil
 }
 
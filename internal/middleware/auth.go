@@ -0,0 +1,60 @@
+package middleware
+
+import (
+  "context"
+  "net/http"
+  "strings"
+
+  "go-url-shortener/internal/auth"
+)
+
+// Context key used to store the authenticated user's id
+type ctxKey string
+
+const userIDKey ctxKey = "user_id"
+
+// Extracts and validates the bearer JWT, attaching the caller's user id
+// to the request context before calling the next handler
+func RequireAuth(next http.HandlerFunc) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    authHeader := r.Header.Get("Authorization")
+    if !strings.HasPrefix(authHeader, "Bearer ") {
+      http.Error(w, "Missing or invalid Authorization header", http.StatusUnauthorized)
+      return
+    }
+
+    tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+    userID, err := auth.ParseToken(tokenString)
+    if err != nil {
+      http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+      return
+    }
+
+    ctx := context.WithValue(r.Context(), userIDKey, userID)
+    next(w, r.WithContext(ctx))
+  }
+}
+
+// Attaches the caller's user id to the request context when a valid bearer
+// JWT is present, but never rejects the request. Used by routes that accept
+// both authenticated and anonymous callers (e.g. link generation), where an
+// authenticated caller gets their links recorded against their user_id and
+// an anonymous caller just gets an unowned link
+func OptionalAuth(next http.HandlerFunc) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    authHeader := r.Header.Get("Authorization")
+    if strings.HasPrefix(authHeader, "Bearer ") {
+      tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+      if userID, err := auth.ParseToken(tokenString); err == nil {
+        r = r.WithContext(context.WithValue(r.Context(), userIDKey, userID))
+      }
+    }
+    next(w, r)
+  }
+}
+
+// Returns the authenticated user id stored on the request context
+func UserIDFromContext(ctx context.Context) (int64, bool) {
+  userID, ok := ctx.Value(userIDKey).(int64)
+  return userID, ok
+}
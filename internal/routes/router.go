@@ -3,19 +3,30 @@ package routes
 import(
   "github.com/gorilla/mux"
   "go-url-shortener/internal/handlers"
+  "go-url-shortener/internal/middleware"
 )
 
-func SetupRouter(dbPath, redisAddr string) (*mux.Router, error) {
+func SetupRouter(driver, dsn, redisAddr string) (*mux.Router, error) {
   r := mux.NewRouter()
 
-  shortenerHandler, err := handlers.NewURLShortenerHandler(dbPath, redisAddr)
+  shortenerHandler, err := handlers.NewURLShortenerHandler(driver, dsn, redisAddr)
   if err != nil {
     return nil, err
   }
 
+  // Auth routes
+  r.HandleFunc("/auth/register", shortenerHandler.RegisterHandler).Methods("POST")
+  r.HandleFunc("/auth/login", shortenerHandler.LoginHandler).Methods("POST")
+
   // Routes
-  r.HandleFunc("/generate", shortenerHandler.GenerateHandler).Methods("POST")
+  r.HandleFunc("/generate", middleware.OptionalAuth(shortenerHandler.GenerateHandler)).Methods("POST")
+  r.HandleFunc("/generate/batch", middleware.OptionalAuth(shortenerHandler.BatchGenerateHandler)).Methods("POST")
+  r.HandleFunc("/links", middleware.RequireAuth(shortenerHandler.ListLinksHandler)).Methods("GET")
   r.HandleFunc("/{shortURL}", shortenerHandler.ResolveHandler).Methods("GET")
+  r.HandleFunc("/{shortURL}", middleware.RequireAuth(shortenerHandler.DeleteLinkHandler)).Methods("DELETE")
+  r.HandleFunc("/{shortURL}/stats", shortenerHandler.StatsHandler).Methods("GET")
+  r.HandleFunc("/{shortURL}/unlock", shortenerHandler.UnlockHandler).Methods("POST")
+  r.HandleFunc("/{shortURL}/qr", shortenerHandler.QRCodeHandler).Methods("GET")
 
   return r, nil
 }
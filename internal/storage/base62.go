@@ -0,0 +1,28 @@
+package storage
+
+import "strings"
+
+// Same alphabet as handlers.encodeBase62 (kept in sync manually, since the
+// two packages can't share the helper without an import cycle)
+const base62Charset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// Decodes a base62-encoded short URL back into its numeric id. Returns
+// ok=false for any short URL containing characters outside the base62
+// alphabet (e.g. a custom short URL using '-'/'_'), since those were never
+// generated from a numeric id in the first place
+func decodeBase62(s string) (int64, bool) {
+  if s == "" {
+    return 0, false
+  }
+
+  var id int64
+  base := int64(len(base62Charset))
+  for _, char := range s {
+    pos := strings.IndexRune(base62Charset, char)
+    if pos < 0 {
+      return 0, false
+    }
+    id = id*base + int64(pos)
+  }
+  return id, true
+}
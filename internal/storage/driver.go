@@ -0,0 +1,54 @@
+package storage
+
+import (
+  "database/sql"
+  "fmt"
+
+  _ "github.com/mattn/go-sqlite3"
+)
+
+// Opens the database connection for the given driver ("sqlite", "postgres",
+// or "memory") and wraps it in the matching Storage implementation. dsn is a
+// file path for sqlite and a connection string for postgres; it is ignored
+// for memory. The returned *sql.DB is the same connection the Storage uses
+// for sqlite and postgres, and an in-memory SQLite connection for "memory",
+// since the users/clicks tables aren't part of the Storage abstraction yet
+// and still need somewhere to live.
+func Open(driver, dsn string) (Storage, *sql.DB, error) {
+  switch driver {
+  case "", "sqlite":
+    db, err := sql.Open("sqlite3", dsn)
+    if err != nil {
+      return nil, nil, err
+    }
+    store, err := NewSQLiteStorage(db)
+    if err != nil {
+      return nil, nil, err
+    }
+    return store, db, nil
+
+  case "memory":
+    db, err := sql.Open("sqlite3", ":memory:")
+    if err != nil {
+      return nil, nil, err
+    }
+    // Every new ":memory:" connection is a separate, empty database, so pin
+    // the pool to one connection to keep the aux tables visible to all queries
+    db.SetMaxOpenConns(1)
+    return NewInMemoryStorage(), db, nil
+
+  case "postgres":
+    db, err := sql.Open("postgres", dsn)
+    if err != nil {
+      return nil, nil, err
+    }
+    store, err := NewPostgresStorage(db)
+    if err != nil {
+      return nil, nil, err
+    }
+    return store, db, nil
+
+  default:
+    return nil, nil, fmt.Errorf("Unknown STORAGE_DRIVER: %q", driver)
+  }
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+  "context"
+  "sync"
+  "time"
+)
+
+// In-memory Storage implementation, used by tests that don't want to touch disk
+type InMemoryStorage struct {
+  mu      sync.Mutex
+  records map[string]URLRecord
+}
+
+func NewInMemoryStorage() *InMemoryStorage {
+  return &InMemoryStorage{
+    records: make(map[string]URLRecord),
+  }
+}
+
+func (s *InMemoryStorage) Put(ctx context.Context, record URLRecord) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  if existing, ok := s.records[record.ShortURL]; ok && !existing.IsDeleted {
+    return ErrShortURLExists
+  }
+
+  s.records[record.ShortURL] = record
+  return nil
+}
+
+func (s *InMemoryStorage) Get(ctx context.Context, shortURL string) (URLRecord, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  record, ok := s.records[shortURL]
+  if !ok || record.IsDeleted {
+    return URLRecord{}, ErrNotFound
+  }
+  return record, nil
+}
+
+func (s *InMemoryStorage) Exists(ctx context.Context, shortURL string) (bool, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  _, ok := s.records[shortURL]
+  return ok, nil
+}
+
+func (s *InMemoryStorage) DeleteExpired(ctx context.Context, before time.Time) ([]string, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  var expired []string
+  for shortURL, record := range s.records {
+    if record.ExpirationTime != nil && record.ExpirationTime.Before(before) {
+      expired = append(expired, shortURL)
+      delete(s.records, shortURL)
+    }
+  }
+  return expired, nil
+}
+
+func (s *InMemoryStorage) BatchPut(ctx context.Context, records []URLRecord) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  for _, record := range records {
+    if existing, ok := s.records[record.ShortURL]; ok && !existing.IsDeleted {
+      return ErrShortURLExists
+    }
+  }
+  for _, record := range records {
+    s.records[record.ShortURL] = record
+  }
+  return nil
+}
+
+func (s *InMemoryStorage) ListByUser(ctx context.Context, userID int64) ([]URLRecord, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  var records []URLRecord
+  for _, record := range s.records {
+    if record.UserID == userID && !record.IsDeleted {
+      records = append(records, record)
+    }
+  }
+  return records, nil
+}
+
+func (s *InMemoryStorage) FindByOwner(ctx context.Context, originalURL string, userID int64) (URLRecord, bool, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  for _, record := range s.records {
+    if record.OriginalURL == originalURL && record.UserID == userID && !record.IsDeleted {
+      return record, true, nil
+    }
+  }
+  return URLRecord{}, false, nil
+}
+
+func (s *InMemoryStorage) SoftDelete(ctx context.Context, userID int64, shortURL string) (bool, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  record, ok := s.records[shortURL]
+  if !ok || record.IsDeleted || record.UserID != userID {
+    return false, nil
+  }
+
+  record.IsDeleted = true
+  s.records[shortURL] = record
+  return true, nil
+}
+
+func (s *InMemoryStorage) MaxShortURLID(ctx context.Context) (int64, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  var maxID int64
+  for shortURL := range s.records {
+    if id, ok := decodeBase62(shortURL); ok && id > maxID {
+      maxID = id
+    }
+  }
+  return maxID, nil
+}
+
+func (s *InMemoryStorage) RecordUse(ctx context.Context, shortURL string) (bool, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  record, ok := s.records[shortURL]
+  if !ok || record.IsDeleted || record.MaxUses == nil || record.UseCount >= *record.MaxUses {
+    return false, nil
+  }
+
+  record.UseCount++
+  s.records[shortURL] = record
+  return true, nil
+}
@@ -0,0 +1,217 @@
+package storage
+
+import (
+  "context"
+  "database/sql"
+  "errors"
+  "fmt"
+  "time"
+
+  "github.com/lib/pq"
+)
+
+// Storage backed by a Postgres database, for multi-instance deployments
+type PostgresStorage struct {
+  db *sql.DB
+}
+
+// Wraps an already-open Postgres database and ensures the urls table exists
+func NewPostgresStorage(db *sql.DB) (*PostgresStorage, error) {
+  createTableQuery := `
+  CREATE TABLE IF NOT EXISTS urls (
+    id SERIAL PRIMARY KEY,
+    short_url TEXT NOT NULL UNIQUE,
+    original_url TEXT NOT NULL,
+    expiration_time TIMESTAMP NULL,
+    user_id INTEGER NULL,
+    is_deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    password_hash TEXT NULL,
+    max_uses INTEGER NULL,
+    use_count INTEGER NOT NULL DEFAULT 0
+  );`
+  if _, err := db.Exec(createTableQuery); err != nil {
+    return nil, err
+  }
+
+  return &PostgresStorage{db: db}, nil
+}
+
+func (s *PostgresStorage) Put(ctx context.Context, record URLRecord) error {
+  exists, err := s.Exists(ctx, record.ShortURL)
+  if err != nil {
+    return err
+  }
+  if exists {
+    return ErrShortURLExists
+  }
+
+  ownerID := sql.NullInt64{Int64: record.UserID, Valid: record.UserID != 0}
+  insertQuery := `INSERT INTO urls (short_url, original_url, expiration_time, user_id, password_hash, max_uses) VALUES ($1, $2, $3, $4, $5, $6)`
+  _, err = s.db.ExecContext(ctx, insertQuery, record.ShortURL, record.OriginalURL, record.ExpirationTime, ownerID, nullablePasswordHash(record.PasswordHash), nullableMaxUses(record.MaxUses))
+  if err != nil {
+    if s.isUniqueViolation(err) {
+      return ErrShortURLExists
+    }
+    return fmt.Errorf("Failed to store short URL: %v", err)
+  }
+
+  return nil
+}
+
+// Reports whether err is a unique-constraint violation (Postgres error
+// code 23505), so a race between the Exists check and the insert (two
+// concurrent requests for the same custom short URL) still maps to
+// ErrShortURLExists instead of a raw driver error
+func (s *PostgresStorage) isUniqueViolation(err error) bool {
+  var pqErr *pq.Error
+  return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func (s *PostgresStorage) Get(ctx context.Context, shortURL string) (URLRecord, error) {
+  query := `SELECT ` + urlRecordColumns + ` FROM urls WHERE short_url = $1 AND is_deleted = FALSE`
+  record, err := scanURLRecord(s.db.QueryRowContext(ctx, query, shortURL))
+  if err == sql.ErrNoRows {
+    return URLRecord{}, ErrNotFound
+  } else if err != nil {
+    return URLRecord{}, fmt.Errorf("Database error: %v", err)
+  }
+
+  return record, nil
+}
+
+func (s *PostgresStorage) Exists(ctx context.Context, shortURL string) (bool, error) {
+  var exists bool
+  query := `SELECT EXISTS(SELECT 1 FROM urls WHERE short_url = $1)`
+  err := s.db.QueryRowContext(ctx, query, shortURL).Scan(&exists)
+  if err != nil {
+    return false, fmt.Errorf("Database error: %v", err)
+  }
+  return exists, nil
+}
+
+func (s *PostgresStorage) DeleteExpired(ctx context.Context, before time.Time) ([]string, error) {
+  rows, err := s.db.QueryContext(ctx, `SELECT short_url FROM urls WHERE expiration_time IS NOT NULL AND expiration_time < $1`, before)
+  if err != nil {
+    return nil, fmt.Errorf("Database error: %v", err)
+  }
+
+  var shortURLs []string
+  for rows.Next() {
+    var shortURL string
+    if err := rows.Scan(&shortURL); err == nil {
+      shortURLs = append(shortURLs, shortURL)
+    }
+  }
+  rows.Close()
+
+  if _, err := s.db.ExecContext(ctx, `DELETE FROM urls WHERE expiration_time IS NOT NULL AND expiration_time < $1`, before); err != nil {
+    return nil, fmt.Errorf("Database error: %v", err)
+  }
+
+  return shortURLs, nil
+}
+
+func (s *PostgresStorage) BatchPut(ctx context.Context, records []URLRecord) error {
+  tx, err := s.db.BeginTx(ctx, nil)
+  if err != nil {
+    return fmt.Errorf("Failed to start transaction: %v", err)
+  }
+
+  insertQuery := `INSERT INTO urls (short_url, original_url, expiration_time, user_id, password_hash, max_uses) VALUES ($1, $2, $3, $4, $5, $6)`
+  for _, record := range records {
+    ownerID := sql.NullInt64{Int64: record.UserID, Valid: record.UserID != 0}
+    if _, err := tx.ExecContext(ctx, insertQuery, record.ShortURL, record.OriginalURL, record.ExpirationTime, ownerID, nullablePasswordHash(record.PasswordHash), nullableMaxUses(record.MaxUses)); err != nil {
+      tx.Rollback()
+      return fmt.Errorf("Failed to insert %s: %v", record.ShortURL, err)
+    }
+  }
+
+  if err := tx.Commit(); err != nil {
+    return fmt.Errorf("Failed to commit transaction: %v", err)
+  }
+
+  return nil
+}
+
+func (s *PostgresStorage) ListByUser(ctx context.Context, userID int64) ([]URLRecord, error) {
+  query := `SELECT ` + urlRecordColumns + ` FROM urls WHERE user_id = $1 AND is_deleted = FALSE`
+  rows, err := s.db.QueryContext(ctx, query, userID)
+  if err != nil {
+    return nil, fmt.Errorf("Database error: %v", err)
+  }
+  defer rows.Close()
+
+  var records []URLRecord
+  for rows.Next() {
+    record, err := scanURLRecord(rows)
+    if err != nil {
+      return nil, fmt.Errorf("Failed to scan record: %v", err)
+    }
+    records = append(records, record)
+  }
+
+  return records, nil
+}
+
+func (s *PostgresStorage) FindByOwner(ctx context.Context, originalURL string, userID int64) (URLRecord, bool, error) {
+  query := `SELECT ` + urlRecordColumns + ` FROM urls WHERE original_url = $1 AND user_id = $2 AND is_deleted = FALSE`
+  record, err := scanURLRecord(s.db.QueryRowContext(ctx, query, originalURL, userID))
+  if err == sql.ErrNoRows {
+    return URLRecord{}, false, nil
+  } else if err != nil {
+    return URLRecord{}, false, fmt.Errorf("Database error: %v", err)
+  }
+
+  return record, true, nil
+}
+
+func (s *PostgresStorage) SoftDelete(ctx context.Context, userID int64, shortURL string) (bool, error) {
+  query := `UPDATE urls SET is_deleted = TRUE WHERE short_url = $1 AND user_id = $2 AND is_deleted = FALSE`
+  result, err := s.db.ExecContext(ctx, query, shortURL, userID)
+  if err != nil {
+    return false, fmt.Errorf("Database error: %v", err)
+  }
+
+  rowsAffected, err := result.RowsAffected()
+  if err != nil {
+    return false, fmt.Errorf("Database error: %v", err)
+  }
+
+  return rowsAffected > 0, nil
+}
+
+func (s *PostgresStorage) MaxShortURLID(ctx context.Context) (int64, error) {
+  rows, err := s.db.QueryContext(ctx, `SELECT short_url FROM urls`)
+  if err != nil {
+    return 0, fmt.Errorf("Database error: %v", err)
+  }
+  defer rows.Close()
+
+  var maxID int64
+  for rows.Next() {
+    var shortURL string
+    if err := rows.Scan(&shortURL); err != nil {
+      return 0, fmt.Errorf("Failed to scan short_url: %v", err)
+    }
+    if id, ok := decodeBase62(shortURL); ok && id > maxID {
+      maxID = id
+    }
+  }
+
+  return maxID, nil
+}
+
+func (s *PostgresStorage) RecordUse(ctx context.Context, shortURL string) (bool, error) {
+  query := `UPDATE urls SET use_count = use_count + 1 WHERE short_url = $1 AND is_deleted = FALSE AND max_uses IS NOT NULL AND use_count < max_uses`
+  result, err := s.db.ExecContext(ctx, query, shortURL)
+  if err != nil {
+    return false, fmt.Errorf("Database error: %v", err)
+  }
+
+  rowsAffected, err := result.RowsAffected()
+  if err != nil {
+    return false, fmt.Errorf("Database error: %v", err)
+  }
+
+  return rowsAffected > 0, nil
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+  "context"
+  "database/sql"
+  "errors"
+  "time"
+)
+
+// Returned by Get when no matching, non-deleted row exists (FindByOwner
+// reports a miss through its bool result instead)
+var ErrNotFound = errors.New("short URL not found")
+
+// Returned by Put when the short URL is already taken
+var ErrShortURLExists = errors.New("short URL already exists")
+
+// A single row of the urls table, independent of the backing driver
+type URLRecord struct {
+  ShortURL       string
+  OriginalURL    string
+  ExpirationTime *time.Time
+  UserID         int64 // 0 means no owner
+  IsDeleted      bool
+  PasswordHash   string // empty means the link isn't password-protected
+  MaxUses        *int64 // nil means unlimited uses
+  UseCount       int64
+}
+
+// Abstracts the urls table over a concrete SQL backend, so URLShortener can
+// be pointed at SQLite, Postgres, or an in-memory store (for tests) via the
+// STORAGE_DRIVER env var without changing any handler code
+type Storage interface {
+  // Inserts a new record, returning ErrShortURLExists on a duplicate short URL
+  Put(ctx context.Context, record URLRecord) error
+
+  // Looks up a non-deleted record by its short URL
+  Get(ctx context.Context, shortURL string) (URLRecord, error)
+
+  // Reports whether a short URL is already taken, deleted or not
+  Exists(ctx context.Context, shortURL string) (bool, error)
+
+  // Deletes all records, soft-deleted or not, whose expiration time is before `before`,
+  // returning the short URLs that were removed so callers can evict caches
+  DeleteExpired(ctx context.Context, before time.Time) ([]string, error)
+
+  // Inserts many records in a single transaction
+  BatchPut(ctx context.Context, records []URLRecord) error
+
+  // Lists the non-deleted records owned by userID
+  ListByUser(ctx context.Context, userID int64) ([]URLRecord, error)
+
+  // Finds a non-deleted record owned by userID for the given original URL
+  FindByOwner(ctx context.Context, originalURL string, userID int64) (URLRecord, bool, error)
+
+  // Marks a record owned by userID as deleted, reporting whether a row was affected
+  SoftDelete(ctx context.Context, userID int64, shortURL string) (bool, error)
+
+  // Atomically increments use_count for a short URL that has max_uses set,
+  // succeeding only while use_count < max_uses. Reports false once the
+  // link's uses are exhausted (or it has no max_uses configured)
+  RecordUse(ctx context.Context, shortURL string) (bool, error)
+
+  // Returns the highest numeric id encoded in any short URL currently stored
+  // (including soft-deleted ones; rows already removed by DeleteExpired are
+  // not counted), so the id generator can reseed itself
+  // after its counter resets without colliding with already-issued codes.
+  // Short URLs that aren't valid base62 (e.g. custom ones using '-'/'_')
+  // are ignored
+  MaxShortURLID(ctx context.Context) (int64, error)
+}
+
+// Scans a *sql.Row or *sql.Rows into a URLRecord, translating the nullable
+// password_hash/max_uses columns into their Go representations
+type rowScanner interface {
+  Scan(dest ...interface{}) error
+}
+
+func scanURLRecord(row rowScanner) (URLRecord, error) {
+  var record URLRecord
+  var passwordHash sql.NullString
+  var maxUses sql.NullInt64
+
+  err := row.Scan(&record.ShortURL, &record.OriginalURL, &record.ExpirationTime, &record.UserID, &passwordHash, &maxUses, &record.UseCount)
+  if err != nil {
+    return URLRecord{}, err
+  }
+
+  record.PasswordHash = passwordHash.String
+  if maxUses.Valid {
+    record.MaxUses = &maxUses.Int64
+  }
+
+  return record, nil
+}
+
+// Column list shared by Get/ListByUser/FindByOwner across backends
+const urlRecordColumns = "short_url, original_url, expiration_time, COALESCE(user_id, 0), password_hash, max_uses, use_count"
+
+func nullablePasswordHash(hash string) sql.NullString {
+  return sql.NullString{String: hash, Valid: hash != ""}
+}
+
+func nullableMaxUses(maxUses *int64) sql.NullInt64 {
+  if maxUses == nil {
+    return sql.NullInt64{}
+  }
+  return sql.NullInt64{Int64: *maxUses, Valid: true}
+}